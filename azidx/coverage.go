@@ -0,0 +1,333 @@
+package azidx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// CoverageOptions configures how the coverage is measured.
+type CoverageOptions struct {
+	// ExercisedVersionsOnly only counts the operations of the API versions that are hit at least once.
+	// Otherwise, the operations of all the API versions in the index are counted, which usually results into a tiny coverage.
+	ExercisedVersionsOnly bool
+}
+
+// Coverage measures which operations in the index are exercised by the recorded requests.
+// An operation is identified by its operation locator and operation ref, where the path patterns (e.g. for different scopes, or expanded from an enum) of the same ref are regarded as the same operation.
+type Coverage struct {
+	index     *Index
+	opts      CoverageOptions
+	hits      map[coverageKey]int
+	unmatched int
+	lroPolls  int
+}
+
+type coverageKey struct {
+	OpLocator
+	Ref string
+}
+
+func NewCoverage(index *Index, opts CoverageOptions) *Coverage {
+	return &Coverage{
+		index: index,
+		opts:  opts,
+		hits:  map[coverageKey]int{},
+	}
+}
+
+// Record looks up the request and records the hit of the matched operation.
+func (c *Coverage) Record(method string, uRL url.URL) (*LookupResult, error) {
+	result, err := c.index.LookupOperation(method, uRL)
+	if err != nil {
+		if errors.Is(err, ErrLROPoll) {
+			c.lroPolls++
+		} else {
+			c.unmatched++
+		}
+		return nil, err
+	}
+	c.hits[coverageKey{OpLocator: result.OpLocator, Ref: result.Ref.String()}]++
+	return result, nil
+}
+
+// CoverageReport is the coverage report, broken down by RP, API version, RT and method/action.
+type CoverageReport struct {
+	CoverageStat
+	// Unmatched is the count of the requests that match no operation in the index.
+	Unmatched int `json:"unmatched"`
+	// LROPolls is the count of the LRO polling requests that match nothing, which are not regarded as unmatched.
+	LROPolls          int           `json:"lro_polls,omitempty"`
+	ResourceProviders []*RPCoverage `json:"resource_providers"`
+	// UncoveredOperations are the operations that are never hit, see Uncovered.
+	UncoveredOperations []UncoveredOperation `json:"uncovered"`
+}
+
+type CoverageStat struct {
+	Total      int     `json:"total"`
+	Covered    int     `json:"covered"`
+	Percentage float64 `json:"percentage"`
+}
+
+func (s *CoverageStat) add(o CoverageStat) {
+	s.Total += o.Total
+	s.Covered += o.Covered
+	s.Percentage = percentage(s.Covered, s.Total)
+}
+
+type RPCoverage struct {
+	Name string `json:"name"`
+	CoverageStat
+	APIVersions []*APIVersionCoverage `json:"api_versions"`
+}
+
+type APIVersionCoverage struct {
+	Version string `json:"version"`
+	CoverageStat
+	ResourceTypes []*RTCoverage `json:"resource_types"`
+}
+
+type RTCoverage struct {
+	Name string `json:"name"`
+	CoverageStat
+	Operations []*OperationCoverage `json:"operations"`
+}
+
+type OperationCoverage struct {
+	Method OperationKind `json:"method"`
+	Action string        `json:"action,omitempty"`
+	Ref    string        `json:"ref"`
+	Hits   int           `json:"hits"`
+}
+
+// UncoveredOperation is an operation that is never hit.
+type UncoveredOperation struct {
+	RP      string        `json:"rp"`
+	Version string        `json:"version"`
+	RT      string        `json:"rt"`
+	Action  string        `json:"action,omitempty"`
+	Method  OperationKind `json:"method"`
+	Ref     string        `json:"ref"`
+}
+
+func percentage(covered, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(covered) * 100 / float64(total)
+}
+
+// Report builds the coverage report of the requests recorded so far.
+func (c *Coverage) Report() *CoverageReport {
+	exercisedVersions := map[string]map[string]bool{}
+	for k := range c.hits {
+		if exercisedVersions[k.RP] == nil {
+			exercisedVersions[k.RP] = map[string]bool{}
+		}
+		exercisedVersions[k.RP][k.Version] = true
+	}
+
+	report := &CoverageReport{Unmatched: c.unmatched, LROPolls: c.lroPolls}
+	for _, rpName := range sortedKeys(c.index.ResourceProviders) {
+		rpCov := &RPCoverage{Name: rpName}
+		versions := c.index.ResourceProviders[rpName]
+		for _, version := range sortedKeys(versions) {
+			if c.opts.ExercisedVersionsOnly && !exercisedVersions[rpName][version] {
+				continue
+			}
+			verCov := &APIVersionCoverage{Version: version}
+			rts := map[string]*RTCoverage{}
+			methods := versions[version]
+			for _, method := range sortedKeys(methods) {
+				for rt, info := range methods[method] {
+					rtCov, ok := rts[rt]
+					if !ok {
+						rtCov = &RTCoverage{Name: rt}
+						rts[rt] = rtCov
+					}
+					addOps := func(act string, refs OperationRefs) {
+						seen := map[string]bool{}
+						for _, ref := range refs {
+							refStr := ref.String()
+							if seen[refStr] {
+								continue
+							}
+							seen[refStr] = true
+							loc := OpLocator{RP: rpName, Version: version, RT: rt, ACT: act, Method: method}
+							hits := c.hits[coverageKey{OpLocator: loc, Ref: refStr}]
+							rtCov.Operations = append(rtCov.Operations, &OperationCoverage{
+								Method: method,
+								Action: act,
+								Ref:    refStr,
+								Hits:   hits,
+							})
+							rtCov.Total++
+							if hits != 0 {
+								rtCov.Covered++
+							}
+						}
+					}
+					addOps("", info.OperationRefs)
+					for act, refs := range info.Actions {
+						addOps(act, refs)
+					}
+				}
+			}
+			for _, rt := range sortedKeys(rts) {
+				rtCov := rts[rt]
+				rtCov.Percentage = percentage(rtCov.Covered, rtCov.Total)
+				sort.Slice(rtCov.Operations, func(i, j int) bool {
+					oi, oj := rtCov.Operations[i], rtCov.Operations[j]
+					if oi.Method != oj.Method {
+						return oi.Method < oj.Method
+					}
+					if oi.Action != oj.Action {
+						return oi.Action < oj.Action
+					}
+					return oi.Ref < oj.Ref
+				})
+				verCov.ResourceTypes = append(verCov.ResourceTypes, rtCov)
+				verCov.add(rtCov.CoverageStat)
+			}
+			rpCov.APIVersions = append(rpCov.APIVersions, verCov)
+			rpCov.add(verCov.CoverageStat)
+		}
+		if len(rpCov.APIVersions) == 0 {
+			continue
+		}
+		report.ResourceProviders = append(report.ResourceProviders, rpCov)
+		report.add(rpCov.CoverageStat)
+	}
+	report.UncoveredOperations = report.Uncovered()
+	return report
+}
+
+// Uncovered returns the operations that are never hit.
+func (r CoverageReport) Uncovered() []UncoveredOperation {
+	var out []UncoveredOperation
+	for _, rp := range r.ResourceProviders {
+		for _, ver := range rp.APIVersions {
+			for _, rt := range ver.ResourceTypes {
+				for _, op := range rt.Operations {
+					if op.Hits != 0 {
+						continue
+					}
+					out = append(out, UncoveredOperation{
+						RP:      rp.Name,
+						Version: ver.Version,
+						RT:      rt.Name,
+						Action:  op.Action,
+						Method:  op.Method,
+						Ref:     op.Ref,
+					})
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Markdown renders the report in markdown.
+func (r CoverageReport) Markdown() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Coverage\n\n")
+	fmt.Fprintf(&sb, "Covered %d of %d operations (%.2f%%), %d request(s) match nothing.\n\n", r.Covered, r.Total, r.Percentage, r.Unmatched)
+	if r.LROPolls != 0 {
+		fmt.Fprintf(&sb, "%d LRO polling request(s) excluded.\n\n", r.LROPolls)
+	}
+	fmt.Fprintf(&sb, "| RP | Covered | Total | Percentage |\n|---|---|---|---|\n")
+	for _, rp := range r.ResourceProviders {
+		fmt.Fprintf(&sb, "| %s | %d | %d | %.2f%% |\n", rp.Name, rp.Covered, rp.Total, rp.Percentage)
+	}
+	for _, rp := range r.ResourceProviders {
+		fmt.Fprintf(&sb, "\n## %s\n", rp.Name)
+		for _, ver := range rp.APIVersions {
+			fmt.Fprintf(&sb, "\n### %s (%d/%d, %.2f%%)\n\n", ver.Version, ver.Covered, ver.Total, ver.Percentage)
+			fmt.Fprintf(&sb, "| RT | Method | Action | Hits |\n|---|---|---|---|\n")
+			for _, rt := range ver.ResourceTypes {
+				for _, op := range rt.Operations {
+					fmt.Fprintf(&sb, "| %s | %s | %s | %d |\n", rt.Name, op.Method, op.Action, op.Hits)
+				}
+			}
+		}
+	}
+	if uncovered := r.Uncovered(); len(uncovered) != 0 {
+		fmt.Fprintf(&sb, "\n## Never Hit\n\n")
+		for _, op := range uncovered {
+			fmt.Fprintf(&sb, "- %s %s %s %s %s: `%s`\n", op.RP, op.Version, op.Method, op.RT, op.Action, op.Ref)
+		}
+	}
+	return sb.String()
+}
+
+var coverageHTMLTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Coverage</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 2px 8px; text-align: left; }
+.miss { background-color: #fdd; }
+.hit { background-color: #dfd; }
+</style>
+</head>
+<body>
+<h1>Coverage</h1>
+<p>Covered {{.Covered}} of {{.Total}} operations ({{printf "%.2f" .Percentage}}%), {{.Unmatched}} request(s) match nothing.</p>
+{{if .LROPolls}}<p>{{.LROPolls}} LRO polling request(s) excluded.</p>
+{{end}}<table>
+<tr><th>RP</th><th>Covered</th><th>Total</th><th>Percentage</th></tr>
+{{- range .ResourceProviders}}
+<tr><td><a href="#{{.Name}}">{{.Name}}</a></td><td>{{.Covered}}</td><td>{{.Total}}</td><td>{{printf "%.2f" .Percentage}}%</td></tr>
+{{- end}}
+</table>
+{{- range .ResourceProviders}}
+<h2 id="{{.Name}}">{{.Name}}</h2>
+{{- range .APIVersions}}
+<h3>{{.Version}} ({{.Covered}}/{{.Total}}, {{printf "%.2f" .Percentage}}%)</h3>
+<table>
+<tr><th>RT</th><th>Method</th><th>Action</th><th>Hits</th><th>Ref</th></tr>
+{{- range $rt := .ResourceTypes}}
+{{- range .Operations}}
+<tr class="{{if .Hits}}hit{{else}}miss{{end}}"><td>{{$rt.Name}}</td><td>{{.Method}}</td><td>{{.Action}}</td><td>{{.Hits}}</td><td>{{.Ref}}</td></tr>
+{{- end}}
+{{- end}}
+</table>
+{{- end}}
+{{- end}}
+{{- with .Uncovered}}
+<h2 id="never-hit">Never Hit</h2>
+<table>
+<tr><th>RP</th><th>Version</th><th>Method</th><th>RT</th><th>Action</th><th>Ref</th></tr>
+{{- range .}}
+<tr class="miss"><td>{{.RP}}</td><td>{{.Version}}</td><td>{{.Method}}</td><td>{{.RT}}</td><td>{{.Action}}</td><td>{{.Ref}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// HTML renders the report in HTML.
+func (r CoverageReport) HTML() (string, error) {
+	var buf bytes.Buffer
+	if err := coverageHTMLTemplate.Execute(&buf, r); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func sortedKeys[K ~string, V any](m map[K]V) []K {
+	var keys []K
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
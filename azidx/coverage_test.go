@@ -0,0 +1,85 @@
+package azidx
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCoverage(t *testing.T) {
+	specRoot := "../testdata/spec"
+	idx, err := BuildIndex(specRoot, "", nil)
+	require.NoError(t, err)
+
+	record := func(cov *Coverage) {
+		for _, req := range []struct {
+			method string
+			url    string
+		}{
+			{"PUT", "https://management.azure.com/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15"},
+			{"GET", "https://management.azure.com/providers/Microsoft.Dummy/foos?api-version=2023-05-15"},
+			{"GET", "https://management.azure.com/providers/Microsoft.Dummy/foos?api-version=2023-05-15"},
+			{"GET", "https://management.azure.com/providers/Microsoft.Dummy/foos?api-version=2020-01-01"},
+		} {
+			uRL, err := url.Parse(req.url)
+			require.NoError(t, err)
+			_, _ = cov.Record(req.method, *uRL)
+		}
+	}
+
+	cov := NewCoverage(idx, CoverageOptions{})
+	record(cov)
+	report := cov.Report()
+	require.Equal(t, CoverageStat{Total: 8, Covered: 2, Percentage: 25}, report.CoverageStat)
+	require.Equal(t, 1, report.Unmatched)
+	require.Len(t, report.ResourceProviders, 1)
+	rp := report.ResourceProviders[0]
+	require.Equal(t, "MICROSOFT.DUMMY", rp.Name)
+	require.Len(t, rp.APIVersions, 2)
+	require.Equal(t, CoverageStat{Total: 3}, rp.APIVersions[0].CoverageStat)
+	ver := rp.APIVersions[1]
+	require.Equal(t, "2023-05-15", ver.Version)
+	require.Equal(t, CoverageStat{Total: 5, Covered: 2, Percentage: 40}, ver.CoverageStat)
+	require.Equal(t, "/", ver.ResourceTypes[0].Name)
+	require.Equal(t, []*OperationCoverage{
+		{
+			Method: "GET",
+			Action: "FOOS",
+			Ref:    "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos/get",
+			Hits:   2,
+		},
+	}, ver.ResourceTypes[0].Operations)
+	require.Len(t, report.Uncovered(), 6)
+	require.Equal(t, report.Uncovered(), report.UncoveredOperations)
+	require.Contains(t, report.UncoveredOperations, UncoveredOperation{
+		RP:      "MICROSOFT.DUMMY",
+		Version: "2023-05-15",
+		RT:      "/FOOS",
+		Method:  "GET",
+		Ref:     "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1%7BfooName%7D/get",
+	})
+	b, err := json.Marshal(report)
+	require.NoError(t, err)
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &m))
+	require.Len(t, m["uncovered"], 6)
+
+	md := report.Markdown()
+	require.Contains(t, md, "| MICROSOFT.DUMMY | 2 | 8 | 25.00% |")
+	require.Contains(t, md, "## Never Hit")
+	html, err := report.HTML()
+	require.NoError(t, err)
+	require.Contains(t, html, `<td><a href="#MICROSOFT.DUMMY">MICROSOFT.DUMMY</a></td><td>2</td><td>8</td><td>25.00%</td>`)
+	require.Contains(t, html, `<h2 id="never-hit">Never Hit</h2>`)
+	require.Contains(t, html, `<tr class="miss"><td>MICROSOFT.DUMMY</td><td>2023-05-15</td><td>GET</td><td>/FOOS</td><td></td><td>dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1%7BfooName%7D/get</td></tr>`)
+	require.Equal(t, 6, strings.Count(html[strings.Index(html, "Never Hit"):], `<tr class="miss">`))
+
+	cov = NewCoverage(idx, CoverageOptions{ExercisedVersionsOnly: true})
+	record(cov)
+	report = cov.Report()
+	require.Equal(t, CoverageStat{Total: 5, Covered: 2, Percentage: 40}, report.CoverageStat)
+	require.Len(t, report.ResourceProviders[0].APIVersions, 1)
+}
@@ -0,0 +1,399 @@
+package azidx
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/go-openapi/jsonreference"
+)
+
+// IndexEncoding is the serialization of the index file.
+type IndexEncoding string
+
+const (
+	// IndexEncodingJSON is the (indented) JSON, which is the default.
+	IndexEncodingJSON IndexEncoding = "json"
+	// IndexEncodingGzip is the gzip compressed JSON.
+	IndexEncodingGzip IndexEncoding = "gzip"
+	// IndexEncodingBinary is a compact binary encoding, where all the strings (e.g. refs, path patterns) are interned in a string table.
+	// It is also gzip compressed, as the refs and path patterns are still highly repetitive.
+	IndexEncodingBinary IndexEncoding = "binary"
+)
+
+// binaryIndexMagic is the leading bytes of the (uncompressed) binary encoding.
+var binaryIndexMagic = []byte("AZIDX\x00")
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// EncodeIndex writes the index in the encoding.
+func EncodeIndex(w io.Writer, idx *Index, encoding IndexEncoding) error {
+	switch encoding {
+	case IndexEncodingJSON, "":
+		b, err := json.MarshalIndent(idx, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	case IndexEncodingGzip:
+		zw := gzip.NewWriter(w)
+		if err := json.NewEncoder(zw).Encode(idx); err != nil {
+			return err
+		}
+		return zw.Close()
+	case IndexEncodingBinary:
+		zw := gzip.NewWriter(w)
+		if err := encodeBinaryIndex(zw, idx); err != nil {
+			return err
+		}
+		return zw.Close()
+	default:
+		return fmt.Errorf("unknown index encoding %q", encoding)
+	}
+}
+
+// DecodeIndex reads the index in any of the encodings, which is detected automatically, and checks its format version (see UnmarshalIndex).
+func DecodeIndex(b []byte) (*Index, error) {
+	idx, _, err := DecodeIndexAsIs(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := MigrateIndex(idx, ""); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// DecodeIndexAsIs reads the index in any of the encodings as DecodeIndex does, together with the detected encoding, but leaves the format version as is.
+// This is for migrating the index with the specdir, see MigrateIndex.
+func DecodeIndexAsIs(b []byte) (*Index, IndexEncoding, error) {
+	encoding := IndexEncodingJSON
+	if bytes.HasPrefix(b, gzipMagic) {
+		encoding = IndexEncodingGzip
+		zr, err := gzip.NewReader(bytes.NewReader(b))
+		if err != nil {
+			return nil, "", fmt.Errorf("opening gzip: %v", err)
+		}
+		defer zr.Close()
+		b, err = io.ReadAll(zr)
+		if err != nil {
+			return nil, "", fmt.Errorf("decompressing gzip: %v", err)
+		}
+	}
+	if !bytes.HasPrefix(b, binaryIndexMagic) {
+		var idx Index
+		if err := json.Unmarshal(b, &idx); err != nil {
+			return nil, "", err
+		}
+		return &idx, encoding, nil
+	}
+	idx, err := decodeBinaryIndex(b[len(binaryIndexMagic):])
+	if err != nil {
+		return nil, "", fmt.Errorf("decoding binary index: %v", err)
+	}
+	return idx, IndexEncodingBinary, nil
+}
+
+// The binary encoding is laid out as below, where each integer is an uvarint, each string is an index into the string table, and each map is a count followed by the sorted entries:
+//
+//	magic
+//	string table: count, (length, bytes)...
+//	format version, commit
+//	resource providers: map[rp]map[version]map[method]map[rt](operation refs, map[act]operation refs)
+//	operations: map[ref](path, operation id, flags, final state via, next link name, map[path pattern](rp, rt, act, path pattern))
+//
+// The operation refs is a map[path pattern]ref.
+
+const (
+	binaryFlagDeprecated = 1 << iota
+	binaryFlagLongRunning
+	binaryFlagPageable
+	binaryFlagNamesOnly
+)
+
+type binaryEncoder struct {
+	strs   []string
+	strIdx map[string]int
+	body   bytes.Buffer
+}
+
+func (e *binaryEncoder) uint(v int) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(v))
+	e.body.Write(buf[:n])
+}
+
+func (e *binaryEncoder) str(s string) {
+	idx, ok := e.strIdx[s]
+	if !ok {
+		idx = len(e.strs)
+		e.strs = append(e.strs, s)
+		e.strIdx[s] = idx
+	}
+	e.uint(idx)
+}
+
+func (e *binaryEncoder) oprefs(refs OperationRefs) {
+	e.uint(len(refs))
+	for _, ppattern := range sortedKeys(refs) {
+		ref := refs[ppattern]
+		e.str(string(ppattern))
+		e.str(ref.String())
+	}
+}
+
+func encodeBinaryIndex(w io.Writer, idx *Index) error {
+	e := &binaryEncoder{strIdx: map[string]int{}}
+	e.uint(idx.FormatVersion)
+	e.str(idx.Commit)
+
+	e.uint(len(idx.ResourceProviders))
+	for _, rp := range sortedKeys(idx.ResourceProviders) {
+		e.str(rp)
+		versions := idx.ResourceProviders[rp]
+		e.uint(len(versions))
+		for _, version := range sortedKeys(versions) {
+			e.str(version)
+			methods := versions[version]
+			e.uint(len(methods))
+			for _, method := range sortedKeys(methods) {
+				e.str(string(method))
+				rts := methods[method]
+				e.uint(len(rts))
+				for _, rt := range sortedKeys(rts) {
+					e.str(rt)
+					info := rts[rt]
+					e.oprefs(info.OperationRefs)
+					e.uint(len(info.Actions))
+					for _, act := range sortedKeys(info.Actions) {
+						e.str(act)
+						e.oprefs(info.Actions[act])
+					}
+				}
+			}
+		}
+	}
+
+	e.uint(len(idx.Operations))
+	for _, ref := range sortedKeys(idx.Operations) {
+		meta := idx.Operations[ref]
+		e.str(ref)
+		e.str(meta.Path)
+		e.str(meta.OperationID)
+		var flags int
+		if meta.Deprecated {
+			flags |= binaryFlagDeprecated
+		}
+		if meta.LongRunning {
+			flags |= binaryFlagLongRunning
+		}
+		if meta.Pageable {
+			flags |= binaryFlagPageable
+		}
+		if meta.NamesOnly {
+			flags |= binaryFlagNamesOnly
+		}
+		e.uint(flags)
+		e.str(meta.FinalStateVia)
+		e.str(meta.NextLinkName)
+		e.uint(len(meta.PathPatterns))
+		for _, ppattern := range sortedKeys(meta.PathPatterns) {
+			names := meta.PathPatterns[ppattern]
+			e.str(string(ppattern))
+			e.str(names.RP)
+			e.str(names.RT)
+			e.str(names.ACT)
+			e.str(names.PathPattern)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.Write(binaryIndexMagic); err != nil {
+		return err
+	}
+	var buf [binary.MaxVarintLen64]byte
+	writeUint := func(v int) error {
+		n := binary.PutUvarint(buf[:], uint64(v))
+		_, err := bw.Write(buf[:n])
+		return err
+	}
+	if err := writeUint(len(e.strs)); err != nil {
+		return err
+	}
+	for _, s := range e.strs {
+		if err := writeUint(len(s)); err != nil {
+			return err
+		}
+		if _, err := bw.WriteString(s); err != nil {
+			return err
+		}
+	}
+	if _, err := bw.Write(e.body.Bytes()); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+type binaryDecoder struct {
+	r    *bytes.Reader
+	strs []string
+	err  error
+}
+
+func (d *binaryDecoder) uint() int {
+	if d.err != nil {
+		return 0
+	}
+	v, err := binary.ReadUvarint(d.r)
+	if err != nil {
+		d.err = err
+		return 0
+	}
+	if v > uint64(d.r.Size()) {
+		// None of the integers (counts, lengths, string indexes) can exceed the size of the input
+		d.err = fmt.Errorf("integer %d out of range", v)
+		return 0
+	}
+	return int(v)
+}
+
+func (d *binaryDecoder) str() string {
+	i := d.uint()
+	if d.err != nil {
+		return ""
+	}
+	if i >= len(d.strs) {
+		d.err = fmt.Errorf("string index %d out of range", i)
+		return ""
+	}
+	return d.strs[i]
+}
+
+// ref decodes the ref. A new ref is created for each entry, even for the same string, as the URL of a ref is mutable
+// and must not be shared between the entries.
+func (d *binaryDecoder) ref() jsonreference.Ref {
+	i := d.uint()
+	if d.err != nil {
+		return jsonreference.Ref{}
+	}
+	if i >= len(d.strs) {
+		d.err = fmt.Errorf("string index %d out of range", i)
+		return jsonreference.Ref{}
+	}
+	v, err := url.PathUnescape(d.strs[i])
+	if err != nil {
+		d.err = err
+		return jsonreference.Ref{}
+	}
+	ref, err := jsonreference.New(v)
+	if err != nil {
+		d.err = err
+		return jsonreference.Ref{}
+	}
+	return ref
+}
+
+func (d *binaryDecoder) oprefs() OperationRefs {
+	n := d.uint()
+	refs := make(OperationRefs, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		ppattern := PathPatternStr(d.str())
+		refs[ppattern] = d.ref()
+	}
+	return refs
+}
+
+func decodeBinaryIndex(b []byte) (*Index, error) {
+	d := &binaryDecoder{r: bytes.NewReader(b)}
+
+	n := d.uint()
+	d.strs = make([]string, 0, n)
+	for i := 0; i < n && d.err == nil; i++ {
+		buf := make([]byte, d.uint())
+		if _, err := io.ReadFull(d.r, buf); err != nil {
+			return nil, err
+		}
+		d.strs = append(d.strs, string(buf))
+	}
+
+	idx := &Index{}
+	idx.FormatVersion = d.uint()
+	idx.Commit = d.str()
+
+	idx.ResourceProviders = ResourceProviders{}
+	nrp := d.uint()
+	for i := 0; i < nrp && d.err == nil; i++ {
+		versions := APIVersions{}
+		idx.ResourceProviders[d.str()] = versions
+		nver := d.uint()
+		for j := 0; j < nver && d.err == nil; j++ {
+			methods := APIMethods{}
+			versions[d.str()] = methods
+			nmethod := d.uint()
+			for k := 0; k < nmethod && d.err == nil; k++ {
+				rts := ResourceTypes{}
+				methods[OperationKind(d.str())] = rts
+				nrt := d.uint()
+				for l := 0; l < nrt && d.err == nil; l++ {
+					rt := d.str()
+					info := &OperationInfo{}
+					if refs := d.oprefs(); len(refs) != 0 {
+						info.OperationRefs = refs
+					}
+					if nact := d.uint(); nact != 0 {
+						info.Actions = make(map[string]OperationRefs, nact)
+						for m := 0; m < nact && d.err == nil; m++ {
+							act := d.str()
+							info.Actions[act] = d.oprefs()
+						}
+					}
+					rts[rt] = info
+				}
+			}
+		}
+	}
+
+	if nop := d.uint(); nop != 0 {
+		idx.Operations = make(map[string]*OperationMetadata, nop)
+		for i := 0; i < nop && d.err == nil; i++ {
+			ref := d.str()
+			meta := &OperationMetadata{
+				Path:        d.str(),
+				OperationID: d.str(),
+			}
+			flags := d.uint()
+			meta.Deprecated = flags&binaryFlagDeprecated != 0
+			meta.LongRunning = flags&binaryFlagLongRunning != 0
+			meta.Pageable = flags&binaryFlagPageable != 0
+			meta.NamesOnly = flags&binaryFlagNamesOnly != 0
+			meta.FinalStateVia = d.str()
+			meta.NextLinkName = d.str()
+			npp := d.uint()
+			meta.PathPatterns = make(map[PathPatternStr]OperationNames, npp)
+			for j := 0; j < npp && d.err == nil; j++ {
+				ppattern := PathPatternStr(d.str())
+				meta.PathPatterns[ppattern] = OperationNames{
+					RP:          d.str(),
+					RT:          d.str(),
+					ACT:         d.str(),
+					PathPattern: d.str(),
+				}
+			}
+			idx.Operations[ref] = meta
+		}
+	}
+
+	if d.err != nil {
+		return nil, d.err
+	}
+	if d.r.Len() != 0 {
+		return nil, fmt.Errorf("%d trailing bytes", d.r.Len())
+	}
+	return idx, nil
+}
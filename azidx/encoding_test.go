@@ -0,0 +1,104 @@
+package azidx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecodeIndex(t *testing.T) {
+	idx, err := BuildIndex("../testdata/spec", "", nil)
+	require.NoError(t, err)
+	idx.Commit = "abc"
+	expect, err := json.Marshal(idx)
+	require.NoError(t, err)
+
+	for _, encoding := range []IndexEncoding{IndexEncodingJSON, IndexEncodingGzip, IndexEncodingBinary} {
+		t.Run(string(encoding), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, EncodeIndex(&buf, idx, encoding))
+			decoded, err := DecodeIndex(buf.Bytes())
+			require.NoError(t, err)
+			actual, err := json.Marshal(decoded)
+			require.NoError(t, err)
+			require.JSONEq(t, string(expect), string(actual))
+		})
+	}
+
+	require.Error(t, EncodeIndex(&bytes.Buffer{}, idx, "foo"))
+
+	// Truncated binary index
+	var buf bytes.Buffer
+	require.NoError(t, encodeBinaryIndex(&buf, idx))
+	_, err = DecodeIndex(buf.Bytes()[:buf.Len()-1])
+	require.Error(t, err)
+
+	// The format version is checked for the binary index as well
+	newer := *idx
+	newer.FormatVersion = IndexFormatVersion + 1
+	buf.Reset()
+	require.NoError(t, EncodeIndex(&buf, &newer, IndexEncodingBinary))
+	_, err = DecodeIndex(buf.Bytes())
+	require.ErrorContains(t, err, "is newer than the supported version")
+}
+
+func TestDecodeIndexAsIs(t *testing.T) {
+	specRoot := "../testdata/spec"
+	idx, err := BuildIndexWithOptions(context.Background(), BuildOptions{SpecDir: specRoot, OperationMetadata: true})
+	require.NoError(t, err)
+	v1 := *idx
+	v1.FormatVersion = 0
+	v1.Operations = nil
+
+	for _, encoding := range []IndexEncoding{IndexEncodingJSON, IndexEncodingGzip, IndexEncodingBinary} {
+		t.Run(string(encoding), func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, EncodeIndex(&buf, &v1, encoding))
+			decoded, decodedEncoding, err := DecodeIndexAsIs(buf.Bytes())
+			require.NoError(t, err)
+			require.Equal(t, encoding, decodedEncoding)
+			require.Equal(t, 0, decoded.FormatVersion)
+
+			// The index left as is can be migrated with the spec dir
+			require.NoError(t, MigrateIndex(decoded, specRoot))
+			require.Equal(t, IndexFormatVersion, decoded.FormatVersion)
+			require.Equal(t, idx.Operations, decoded.Operations)
+		})
+	}
+}
+
+func TestDecodeBinaryIndexRefsNotShared(t *testing.T) {
+	ref := jsonreference.MustCreateRef("rp1/foo.json#/paths/~1foos/get")
+	idx := &Index{
+		FormatVersion: IndexFormatVersion,
+		ResourceProviders: ResourceProviders{
+			"RP1": APIVersions{
+				"ver1": APIMethods{
+					"GET": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/PROVIDERS/RP1/FOOS":                  ref,
+								"/SUBSCRIPTIONS/{}/PROVIDERS/RP1/FOOS": ref,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	require.NoError(t, EncodeIndex(&buf, idx, IndexEncodingBinary))
+	decoded, err := DecodeIndex(buf.Bytes())
+	require.NoError(t, err)
+
+	// Changing the ref of one entry doesn't affect the other
+	refs := decoded.ResourceProviders["RP1"]["ver1"]["GET"]["/FOOS"].OperationRefs
+	r1 := refs["/PROVIDERS/RP1/FOOS"]
+	r1.GetURL().Path = "changed.json"
+	r2 := refs["/SUBSCRIPTIONS/{}/PROVIDERS/RP1/FOOS"]
+	require.Equal(t, "rp1/foo.json", r2.GetURL().Path)
+}
@@ -0,0 +1,116 @@
+package azidx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/jsonreference"
+)
+
+// Example is the content of an x-ms-examples file.
+type Example struct {
+	// Parameters maps the parameter names to the values, where the body parameter is keyed by its name.
+	Parameters map[string]interface{} `json:"parameters"`
+	// Responses is keyed by the status code.
+	Responses map[string]ExampleResponse `json:"responses"`
+}
+
+// ExampleResponse is a response of an x-ms-examples file.
+type ExampleResponse struct {
+	Headers map[string]interface{} `json:"headers,omitempty"`
+	Body    json.RawMessage        `json:"body,omitempty"`
+}
+
+// LoadExample loads the example file, whose path is relative to the spec dir (see ResolvedOperation.Examples).
+func LoadExample(specdir, path string) (*Example, error) {
+	b, err := os.ReadFile(filepath.Join(specdir, path))
+	if err != nil {
+		return nil, fmt.Errorf("reading example %s: %v", path, err)
+	}
+	var example Example
+	if err := json.Unmarshal(b, &example); err != nil {
+		return nil, fmt.Errorf("unmarshal example %s: %v", path, err)
+	}
+	return &example, nil
+}
+
+// ExampleFile is an x-ms-examples file of an operation.
+type ExampleFile struct {
+	Title string `json:"title"`
+	// Path is the path of the example file, relative to the spec dir.
+	Path string `json:"path"`
+	// Score is the count of the example's request parameters that match the request, which is only set by RankExamples.
+	Score int `json:"score,omitempty"`
+}
+
+// ExampleFiles returns the example files of the operation, sorted by title.
+func (op ResolvedOperation) ExampleFiles() []ExampleFile {
+	var files []ExampleFile
+	for title, path := range op.Examples {
+		files = append(files, ExampleFile{Title: title, Path: path})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Title < files[j].Title
+	})
+	return files
+}
+
+// Examples returns the example files of the operation that the ref points to, sorted by title.
+func (r *OperationResolver) Examples(ref jsonreference.Ref) ([]ExampleFile, error) {
+	op, err := r.Resolve(ref)
+	if err != nil {
+		return nil, err
+	}
+	return op.ExampleFiles(), nil
+}
+
+// RankExamples scores the example files of the operation by how many of their request parameters (path and query) have the same value as the request URL,
+// and returns them sorted by the score descendingly (then by title).
+// The example file that can't be loaded is kept with the score of 0, as one broken example shouldn't fail the others.
+func RankExamples(specdir string, op *ResolvedOperation, uRL url.URL) ([]ExampleFile, error) {
+	params := requestParameters(op, uRL)
+	files := op.ExampleFiles()
+	for i, file := range files {
+		example, err := LoadExample(specdir, file.Path)
+		if err != nil {
+			logger.Debug("skip ranking the example", "title", file.Title, "error", err)
+			continue
+		}
+		for name, value := range example.Parameters {
+			if v, ok := params[strings.ToLower(name)]; ok && strings.EqualFold(v, fmt.Sprint(value)) {
+				files[i].Score++
+			}
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Score > files[j].Score
+	})
+	return files, nil
+}
+
+// requestParameters returns the (lower cased) names of the path and query parameters mapped to their values in the request URL.
+// The path parameters are extracted by matching the request path against the API path of the operation segment by segment.
+func requestParameters(op *ResolvedOperation, uRL url.URL) map[string]string {
+	params := map[string]string{}
+	for k, v := range uRL.Query() {
+		if len(v) != 0 {
+			params[strings.ToLower(k)] = v[0]
+		}
+	}
+	tsegs := strings.Split(strings.Trim(op.Path, "/"), "/")
+	rsegs := strings.Split(strings.Trim(uRL.Path, "/"), "/")
+	if len(tsegs) != len(rsegs) {
+		return params
+	}
+	for i, tseg := range tsegs {
+		if strings.HasPrefix(tseg, "{") && strings.HasSuffix(tseg, "}") {
+			params[strings.ToLower(strings.Trim(tseg, "{}"))] = rsegs[i]
+		}
+	}
+	return params
+}
@@ -0,0 +1,60 @@
+package azidx
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankExamples(t *testing.T) {
+	specRoot := "../testdata/spec"
+	ref := jsonreference.MustCreateRef("dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1{fooName}/get")
+
+	files, err := NewOperationResolver(specRoot).Examples(ref)
+	require.NoError(t, err)
+	require.Equal(t, []ExampleFile{
+		{Title: "Get a disabled foo", Path: "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/examples/Foos_GetDisabled.json"},
+		{Title: "Get a foo", Path: "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/examples/Foos_Get.json"},
+	}, files)
+
+	op, err := ResolveOperation(specRoot, ref)
+	require.NoError(t, err)
+	uRL, err := url.Parse("/providers/Microsoft.Dummy/foos/FOO1?api-version=2023-05-15")
+	require.NoError(t, err)
+	files, err = RankExamples(specRoot, op, *uRL)
+	require.NoError(t, err)
+	require.Equal(t, []ExampleFile{
+		{Title: "Get a foo", Path: "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/examples/Foos_Get.json", Score: 2},
+		{Title: "Get a disabled foo", Path: "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/examples/Foos_GetDisabled.json", Score: 1},
+	}, files)
+
+	example, err := LoadExample(specRoot, files[0].Path)
+	require.NoError(t, err)
+	require.Equal(t, "foo1", example.Parameters["fooName"])
+	require.Contains(t, example.Responses, "200")
+
+	// The broken example file is skipped, instead of failing the others
+	specdir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(specdir, "good.json"), []byte(`{"parameters": {"fooName": "foo1"}}`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(specdir, "broken.json"), []byte(`{"parameters": `), 0644))
+	files, err = RankExamples(specdir, &ResolvedOperation{
+		Path:     "/providers/Microsoft.Dummy/foos/{fooName}",
+		Examples: map[string]string{"Broken": "broken.json", "Good": "good.json", "Missing": "missing.json"},
+	}, *uRL)
+	require.NoError(t, err)
+	require.Equal(t, []ExampleFile{
+		{Title: "Good", Path: "good.json", Score: 1},
+		{Title: "Broken", Path: "broken.json"},
+		{Title: "Missing", Path: "missing.json"},
+	}, files)
+
+	// The operation without x-ms-examples
+	ref = jsonreference.MustCreateRef("dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos/get")
+	files, err = NewOperationResolver(specRoot).Examples(ref)
+	require.NoError(t, err)
+	require.Empty(t, files)
+}
@@ -0,0 +1,80 @@
+// Package export exports the index to formats that are easier to query than the nested index file, e.g. SQLite.
+package export
+
+import (
+	"net/url"
+	"sort"
+
+	"github.com/magodo/azure-rest-api-index/azidx"
+)
+
+// OperationRow is one operation in the index, i.e. a ref of a path pattern of a (RP, API version, method, RT, action).
+// The RP, RT, ACT and PathPattern are upper cased as in the index.
+type OperationRow struct {
+	RP          string
+	APIVersion  string
+	IsPreview   bool
+	Method      string
+	RT          string
+	ACT         string
+	PathPattern string
+	// Ref is the (unescaped) reference to the operation, e.g. compute/resource-manager/Microsoft.Compute/stable/2020-01-01/compute.json#/paths/~1subscriptions~1{subscriptionId}~1providers~1Microsoft.Compute~1virtualMachines/get
+	Ref string
+	// SpecFile is the spec file part of the Ref, relative to the spec dir.
+	SpecFile string
+	// Pointer is the JSON pointer part of the Ref.
+	Pointer string
+
+	// The followings are from the operation metadata, which are empty if the index has no operation metadata.
+	OperationID string
+	Path        string
+	LongRunning bool
+	Pageable    bool
+	Deprecated  bool
+}
+
+// OperationRows flattens the index into operation rows, sorted by RP, API version, method, RT, action and path pattern.
+func OperationRows(idx *azidx.Index) []OperationRow {
+	ops := idx.Flatten()
+	var rows []OperationRow
+	for _, loc := range ops.SortedLocators() {
+		oprefs := ops[loc]
+		for _, ppattern := range sortedPathPatterns(oprefs) {
+			ref := oprefs[ppattern]
+			refStr := ref.String()
+			row := OperationRow{
+				RP:          loc.RP,
+				APIVersion:  loc.Version,
+				IsPreview:   azidx.IsPreviewAPIVersion(loc.Version),
+				Method:      string(loc.Method),
+				RT:          loc.RT,
+				ACT:         loc.ACT,
+				PathPattern: string(ppattern),
+				Ref:         refStr,
+				SpecFile:    ref.GetURL().Path,
+				Pointer:     ref.GetURL().Fragment,
+			}
+			if v, err := url.PathUnescape(refStr); err == nil {
+				row.Ref = v
+			}
+			if meta, ok := idx.Operations[refStr]; ok {
+				row.OperationID = meta.OperationID
+				row.Path = meta.Path
+				row.LongRunning = meta.LongRunning
+				row.Pageable = meta.Pageable
+				row.Deprecated = meta.Deprecated
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func sortedPathPatterns(oprefs azidx.OperationRefs) []azidx.PathPatternStr {
+	var l []azidx.PathPatternStr
+	for k := range oprefs {
+		l = append(l, k)
+	}
+	sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+	return l
+}
@@ -0,0 +1,145 @@
+package export
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/magodo/azure-rest-api-index/azidx"
+	"github.com/magodo/azure-rest-api-index/azidx/specpath"
+
+	// The pure Go SQLite driver, registered as "sqlite"
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE index_info (
+	commit_id      TEXT,
+	format_version INTEGER NOT NULL
+);
+
+CREATE TABLE spec_files (
+	path        TEXT PRIMARY KEY,
+	service     TEXT,
+	rp          TEXT,
+	subservices TEXT,
+	is_preview  INTEGER,
+	version     TEXT,
+	spec_name   TEXT
+);
+
+CREATE TABLE operations (
+	rp           TEXT NOT NULL,
+	api_version  TEXT NOT NULL,
+	is_preview   INTEGER NOT NULL,
+	method       TEXT NOT NULL,
+	rt           TEXT NOT NULL,
+	act          TEXT NOT NULL,
+	path_pattern TEXT NOT NULL,
+	ref          TEXT NOT NULL,
+	spec_file    TEXT NOT NULL REFERENCES spec_files(path),
+	pointer      TEXT NOT NULL,
+	operation_id TEXT,
+	path         TEXT,
+	long_running INTEGER NOT NULL,
+	pageable     INTEGER NOT NULL,
+	deprecated   INTEGER NOT NULL
+);
+
+CREATE INDEX operations_rp_version ON operations(rp, api_version);
+CREATE INDEX operations_spec_file ON operations(spec_file);
+
+CREATE VIEW api_versions AS
+	SELECT DISTINCT rp, api_version, is_preview FROM operations;
+`
+
+// WriteSQLite writes the index to the SQLite database file at path, which is overwritten if exists.
+//
+// The database has the following tables:
+//
+//   - index_info: The commit and format version of the index.
+//   - operations: One row per operation, see OperationRow.
+//   - spec_files: One row per spec file referenced by the operations, with the information parsed from its path (see specpath.Info).
+//
+// Also, the view api_versions lists the distinct API versions of each RP.
+func WriteSQLite(path string, idx *azidx.Index) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("opening database %s: %v", path, err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		return fmt.Errorf("creating schema: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`INSERT INTO index_info (commit_id, format_version) VALUES (?, ?)`, idx.Commit, idx.FormatVersion); err != nil {
+		return fmt.Errorf("inserting index info: %v", err)
+	}
+
+	rows := OperationRows(idx)
+
+	specFiles := map[string]bool{}
+	for _, row := range rows {
+		specFiles[row.SpecFile] = true
+	}
+	var specPaths []string
+	for p := range specFiles {
+		specPaths = append(specPaths, p)
+	}
+	sort.Strings(specPaths)
+
+	stmt, err := tx.Prepare(`INSERT INTO spec_files (path, service, rp, subservices, is_preview, version, spec_name) VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, p := range specPaths {
+		info, err := specpath.SpecPathInfo(p)
+		if err != nil {
+			// The spec file that is not of the conventional layout only has its path recorded
+			if _, err := stmt.Exec(p, nil, nil, nil, nil, nil, nil); err != nil {
+				return fmt.Errorf("inserting spec file %s: %v", p, err)
+			}
+			continue
+		}
+		if _, err := stmt.Exec(p, info.ResourceProvider, info.ResourceProviderMS, strings.Join(info.Subservices(), "/"), info.IsPreview, info.Version, info.SpecName); err != nil {
+			return fmt.Errorf("inserting spec file %s: %v", p, err)
+		}
+	}
+
+	stmt, err = tx.Prepare(`INSERT INTO operations (rp, api_version, is_preview, method, rt, act, path_pattern, ref, spec_file, pointer, operation_id, path, long_running, pageable, deprecated) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, err := stmt.Exec(row.RP, row.APIVersion, row.IsPreview, row.Method, row.RT, row.ACT, row.PathPattern, row.Ref, row.SpecFile, row.Pointer, nullString(row.OperationID), nullString(row.Path), row.LongRunning, row.Pageable, row.Deprecated); err != nil {
+			return fmt.Errorf("inserting operation %s: %v", row.Ref, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return db.Close()
+}
+
+// nullString returns nil for the empty string, which is stored as NULL.
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,56 @@
+package export
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/magodo/azure-rest-api-index/azidx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteSQLite(t *testing.T) {
+	idx, err := azidx.BuildIndex("../../testdata/spec", "", nil)
+	require.NoError(t, err)
+	idx.Commit = "abc"
+
+	path := filepath.Join(t.TempDir(), "index.db")
+	require.NoError(t, WriteSQLite(path, idx))
+	// Overwriting the existing database
+	require.NoError(t, WriteSQLite(path, idx))
+
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+	defer db.Close()
+
+	var commit string
+	var version int
+	require.NoError(t, db.QueryRow(`SELECT commit_id, format_version FROM index_info`).Scan(&commit, &version))
+	require.Equal(t, "abc", commit)
+	require.Equal(t, azidx.IndexFormatVersion, version)
+
+	var count int
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM operations`).Scan(&count))
+	require.Equal(t, len(OperationRows(idx)), count)
+	require.NotZero(t, count)
+
+	// Each API version of the spec file agrees with the preview flag of the spec file
+	require.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM operations o JOIN spec_files s ON o.spec_file = s.path WHERE o.is_preview != s.is_preview OR o.api_version != s.version`).Scan(&count))
+	require.Zero(t, count)
+
+	rows, err := db.Query(`SELECT api_version, is_preview FROM api_versions WHERE rp = 'MICROSOFT.DUMMY' ORDER BY api_version`)
+	require.NoError(t, err)
+	defer rows.Close()
+	type apiVersion struct {
+		Version   string
+		IsPreview bool
+	}
+	var versions []apiVersion
+	for rows.Next() {
+		var v apiVersion
+		require.NoError(t, rows.Scan(&v.Version, &v.IsPreview))
+		versions = append(versions, v)
+	}
+	require.NoError(t, rows.Err())
+	require.Equal(t, []apiVersion{{"2023-05-01-preview", true}, {"2023-05-15", false}}, versions)
+}
@@ -0,0 +1,132 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/magodo/azure-rest-api-index/azidx"
+)
+
+// columns maps the available columns of the table export to the getters of the operation row.
+var columns = map[string]func(row OperationRow) string{
+	"rp":           func(row OperationRow) string { return row.RP },
+	"api_version":  func(row OperationRow) string { return row.APIVersion },
+	"is_preview":   func(row OperationRow) string { return strconv.FormatBool(row.IsPreview) },
+	"method":       func(row OperationRow) string { return row.Method },
+	"rt":           func(row OperationRow) string { return row.RT },
+	"act":          func(row OperationRow) string { return row.ACT },
+	"path_pattern": func(row OperationRow) string { return row.PathPattern },
+	"ref":          func(row OperationRow) string { return row.Ref },
+	"spec_file":    func(row OperationRow) string { return row.SpecFile },
+	"pointer":      func(row OperationRow) string { return row.Pointer },
+	"operation_id": func(row OperationRow) string { return row.OperationID },
+	"path":         func(row OperationRow) string { return row.Path },
+	"long_running": func(row OperationRow) string { return strconv.FormatBool(row.LongRunning) },
+	"pageable":     func(row OperationRow) string { return strconv.FormatBool(row.Pageable) },
+	"deprecated":   func(row OperationRow) string { return strconv.FormatBool(row.Deprecated) },
+}
+
+// AllColumns are all the available columns of the table export.
+var AllColumns = []string{
+	"rp", "api_version", "is_preview", "method", "rt", "act", "path_pattern", "ref", "spec_file", "pointer",
+	"operation_id", "path", "long_running", "pageable", "deprecated",
+}
+
+// DefaultColumns are the columns of the table export if not specified.
+var DefaultColumns = []string{"rp", "api_version", "method", "rt", "act", "path_pattern", "spec_file", "pointer"}
+
+// Filter selects the operation rows. Each of the non-empty fields has to be matched, where any value of a list is a match.
+// The values are case insensitive.
+type Filter struct {
+	RPs         []string
+	APIVersions []string
+	Methods     []string
+	// RTs are the resource types, e.g. virtualNetworks/subnets. The leading "/" is optional.
+	RTs  []string
+	ACTs []string
+	// Stability selects the API versions: "stable" for the stable versions only, "preview" for the preview versions only, and "any" (or empty) for both.
+	Stability azidx.VersionPolicy
+}
+
+// Match tells whether the row is selected by the filter.
+func (f Filter) Match(row OperationRow) bool {
+	match := func(values []string, v string, normalize func(string) string) bool {
+		if len(values) == 0 {
+			return true
+		}
+		for _, value := range values {
+			if normalize != nil {
+				value = normalize(value)
+			}
+			if strings.EqualFold(value, v) {
+				return true
+			}
+		}
+		return false
+	}
+	normalizeRT := func(rt string) string {
+		return "/" + strings.Trim(rt, "/")
+	}
+	switch f.Stability {
+	case azidx.VersionPolicyStable:
+		if row.IsPreview {
+			return false
+		}
+	case azidx.VersionPolicyPreview:
+		if !row.IsPreview {
+			return false
+		}
+	}
+	return match(f.RPs, row.RP, nil) &&
+		match(f.APIVersions, row.APIVersion, nil) &&
+		match(f.Methods, row.Method, nil) &&
+		match(f.RTs, row.RT, normalizeRT) &&
+		match(f.ACTs, row.ACT, nil)
+}
+
+// FilterRows returns the rows that are selected by the filter.
+func FilterRows(rows []OperationRow, filter Filter) []OperationRow {
+	var out []OperationRow
+	for _, row := range rows {
+		if filter.Match(row) {
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// WriteTable writes the rows of the columns, with a header line, as CSV. The comma is the field delimiter, e.g. '\t' for TSV.
+// The DefaultColumns are used if no column is specified.
+func WriteTable(w io.Writer, rows []OperationRow, cols []string, comma rune) error {
+	if len(cols) == 0 {
+		cols = DefaultColumns
+	}
+	var getters []func(row OperationRow) string
+	for _, col := range cols {
+		getter, ok := columns[col]
+		if !ok {
+			return fmt.Errorf("unknown column %q, available columns: %s", col, strings.Join(AllColumns, ", "))
+		}
+		getters = append(getters, getter)
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(cols); err != nil {
+		return err
+	}
+	record := make([]string, len(getters))
+	for _, row := range rows {
+		for i, getter := range getters {
+			record[i] = getter(row)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
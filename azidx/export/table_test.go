@@ -0,0 +1,34 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/magodo/azure-rest-api-index/azidx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTable(t *testing.T) {
+	rows := []OperationRow{
+		{RP: "MICROSOFT.FOO", APIVersion: "2020-01-01", Method: "GET", RT: "/BARS", PathPattern: "/SUBSCRIPTIONS/{}/PROVIDERS/MICROSOFT.FOO/BARS/{}", SpecFile: "foo/resource-manager/Microsoft.Foo/stable/2020-01-01/foo.json", Pointer: "/paths/~1bars/get"},
+		{RP: "MICROSOFT.FOO", APIVersion: "2021-01-01-preview", IsPreview: true, Method: "POST", RT: "/BARS", ACT: "LISTKEYS", PathPattern: "/SUBSCRIPTIONS/{}/PROVIDERS/MICROSOFT.FOO/BARS/{}/LISTKEYS", OperationID: "Bars_ListKeys, Extra"},
+		{RP: "MICROSOFT.BAZ", APIVersion: "2020-01-01", Method: "GET", RT: "/"},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTable(&buf, FilterRows(rows, Filter{RPs: []string{"Microsoft.Foo"}}), []string{"api_version", "act", "operation_id"}, ','))
+	require.Equal(t, `api_version,act,operation_id
+2020-01-01,,
+2021-01-01-preview,LISTKEYS,"Bars_ListKeys, Extra"
+`, buf.String())
+
+	buf.Reset()
+	require.NoError(t, WriteTable(&buf, FilterRows(rows, Filter{RTs: []string{"bars"}, Stability: azidx.VersionPolicyStable}), nil, '\t'))
+	require.Equal(t, "rp\tapi_version\tmethod\trt\tact\tpath_pattern\tspec_file\tpointer\n"+
+		"MICROSOFT.FOO\t2020-01-01\tGET\t/BARS\t\t/SUBSCRIPTIONS/{}/PROVIDERS/MICROSOFT.FOO/BARS/{}\tfoo/resource-manager/Microsoft.Foo/stable/2020-01-01/foo.json\t/paths/~1bars/get\n", buf.String())
+
+	require.Len(t, FilterRows(rows, Filter{Methods: []string{"post", "put"}, Stability: azidx.VersionPolicyPreview}), 1)
+	require.Len(t, FilterRows(rows, Filter{Methods: []string{"post"}, Stability: azidx.VersionPolicyStable}), 0)
+
+	require.ErrorContains(t, WriteTable(&buf, rows, []string{"foo"}, ','), `unknown column "foo"`)
+}
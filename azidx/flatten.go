@@ -0,0 +1,50 @@
+package azidx
+
+import (
+	"sort"
+)
+
+// Flatten turns the layered index back to the flattened index, keyed by the operation locator.
+func (idx Index) Flatten() FlattenOpIndex {
+	ops := FlattenOpIndex{}
+	for rp, versions := range idx.ResourceProviders {
+		for version, methods := range versions {
+			for method, rts := range methods {
+				for rt, info := range rts {
+					if len(info.OperationRefs) != 0 {
+						ops[OpLocator{RP: rp, Version: version, RT: rt, Method: method}] = info.OperationRefs
+					}
+					for act, oprefs := range info.Actions {
+						ops[OpLocator{RP: rp, Version: version, RT: rt, ACT: act, Method: method}] = oprefs
+					}
+				}
+			}
+		}
+	}
+	return ops
+}
+
+// SortedLocators returns the operation locators, sorted by RP, version, method, RT and ACT.
+func (ops FlattenOpIndex) SortedLocators() []OpLocator {
+	var locs []OpLocator
+	for loc := range ops {
+		locs = append(locs, loc)
+	}
+	sort.Slice(locs, func(i, j int) bool {
+		li, lj := locs[i], locs[j]
+		if li.RP != lj.RP {
+			return li.RP < lj.RP
+		}
+		if li.Version != lj.Version {
+			return li.Version < lj.Version
+		}
+		if li.Method != lj.Method {
+			return li.Method < lj.Method
+		}
+		if li.RT != lj.RT {
+			return li.RT < lj.RT
+		}
+		return li.ACT < lj.ACT
+	})
+	return locs
+}
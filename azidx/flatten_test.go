@@ -0,0 +1,42 @@
+package azidx
+
+import (
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex_Flatten(t *testing.T) {
+	index := Index{
+		ResourceProviders: ResourceProviders{
+			"RP1": APIVersions{
+				"ver1": APIMethods{
+					"GET": ResourceTypes{
+						"/": &OperationInfo{
+							Actions: map[string]OperationRefs{
+								"FOOS": {"/PROVIDERS/RP1/FOOS": jsonreference.MustCreateRef("#P1")},
+							},
+						},
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{"/PROVIDERS/RP1/FOOS/{}": jsonreference.MustCreateRef("#P2")},
+						},
+					},
+					"DELETE": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{"/PROVIDERS/RP1/FOOS/{}": jsonreference.MustCreateRef("#P3")},
+						},
+					},
+				},
+			},
+		},
+	}
+	ops := index.Flatten()
+	require.Equal(t, []OpLocator{
+		{RP: "RP1", Version: "ver1", RT: "/FOOS", Method: "DELETE"},
+		{RP: "RP1", Version: "ver1", RT: "/", ACT: "FOOS", Method: "GET"},
+		{RP: "RP1", Version: "ver1", RT: "/FOOS", Method: "GET"},
+	}, ops.SortedLocators())
+	ref := ops[OpLocator{RP: "RP1", Version: "ver1", RT: "/", ACT: "FOOS", Method: "GET"}]["/PROVIDERS/RP1/FOOS"]
+	require.Equal(t, "#P1", ref.String())
+}
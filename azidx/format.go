@@ -0,0 +1,105 @@
+package azidx
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// IndexFormatVersion is the version of the index format built by this tool.
+//
+// The history of the format versions:
+//
+//	1: The initial format, which has no "format_version" recorded.
+//	2: Added the optional "operations", i.e. the operation metadata.
+const IndexFormatVersion = 2
+
+// indexMigrations migrates the index from the version of the key to the next version.
+var indexMigrations = map[int]func(idx *Index, specdir string) error{
+	1: migrateIndexV1,
+}
+
+// UnmarshalIndex unmarshals the index file content, and checks its format version.
+// The index of a newer format version is rejected, while the index of an older one is migrated in memory.
+func UnmarshalIndex(b []byte) (*Index, error) {
+	var index Index
+	if err := json.Unmarshal(b, &index); err != nil {
+		return nil, err
+	}
+	if err := MigrateIndex(&index, ""); err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
+
+// MigrateIndex migrates the index to the current format version.
+// The specdir is optional, which is used to fill in the information absent in older format versions (e.g. the operation metadata).
+// It has to be of the same commit as the index.
+func MigrateIndex(idx *Index, specdir string) error {
+	// The index of the first format version has no version recorded
+	if idx.FormatVersion == 0 {
+		idx.FormatVersion = 1
+	}
+	if idx.FormatVersion > IndexFormatVersion {
+		return fmt.Errorf("the index format version %d is newer than the supported version %d, please upgrade this tool", idx.FormatVersion, IndexFormatVersion)
+	}
+	for idx.FormatVersion < IndexFormatVersion {
+		migrate, ok := indexMigrations[idx.FormatVersion]
+		if !ok {
+			return fmt.Errorf("no migration from the index format version %d", idx.FormatVersion)
+		}
+		if err := migrate(idx, specdir); err != nil {
+			return fmt.Errorf("migrating the index from the format version %d: %v", idx.FormatVersion, err)
+		}
+		idx.FormatVersion++
+	}
+	return nil
+}
+
+// migrateIndexV1 builds the operation metadata from the spec files referenced by the index, if the specdir is specified.
+// Otherwise, the index is left without operation metadata, which is optional.
+func migrateIndexV1(idx *Index, specdir string) error {
+	if specdir == "" {
+		return nil
+	}
+	specdir, err := filepath.Abs(specdir)
+	if err != nil {
+		return err
+	}
+	files := map[string]bool{}
+	for _, versions := range idx.ResourceProviders {
+		for _, methods := range versions {
+			for _, rts := range methods {
+				for _, info := range rts {
+					for _, ref := range info.OperationRefs {
+						files[ref.GetURL().Path] = true
+					}
+					for _, oprefs := range info.Actions {
+						for _, ref := range oprefs {
+							files[ref.GetURL().Path] = true
+						}
+					}
+				}
+			}
+		}
+	}
+	var paths []string
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	metas := map[string]*OperationMetadata{}
+	for _, p := range paths {
+		_, mmetas, err := parseSpec(specdir, filepath.Join(specdir, p), true)
+		if err != nil {
+			return fmt.Errorf("parsing spec %s: %v", p, err)
+		}
+		for ref, meta := range mmetas {
+			metas[ref] = meta
+		}
+	}
+	idx.attachOperationMetadata(metas)
+	return nil
+}
@@ -0,0 +1,51 @@
+package azidx
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalIndex(t *testing.T) {
+	specRoot := "../testdata/spec"
+	idx, err := BuildIndexWithOptions(context.Background(), BuildOptions{SpecDir: specRoot, OperationMetadata: true})
+	require.NoError(t, err)
+	require.Equal(t, IndexFormatVersion, idx.FormatVersion)
+
+	// The index of the current version
+	b, err := json.Marshal(idx)
+	require.NoError(t, err)
+	loaded, err := UnmarshalIndex(b)
+	require.NoError(t, err)
+	require.Equal(t, IndexFormatVersion, loaded.FormatVersion)
+	require.Equal(t, len(idx.Operations), len(loaded.Operations))
+
+	// The index of the first version, which has no format version and operation metadata
+	v1 := *idx
+	v1.FormatVersion = 0
+	v1.Operations = nil
+	b, err = json.Marshal(v1)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "format_version")
+	loaded, err = UnmarshalIndex(b)
+	require.NoError(t, err)
+	require.Equal(t, IndexFormatVersion, loaded.FormatVersion)
+	require.Nil(t, loaded.Operations)
+
+	// Migrating with the spec dir fills in the operation metadata
+	var migrated Index
+	require.NoError(t, json.Unmarshal(b, &migrated))
+	require.NoError(t, MigrateIndex(&migrated, specRoot))
+	require.Equal(t, IndexFormatVersion, migrated.FormatVersion)
+	require.Equal(t, idx.Operations, migrated.Operations)
+
+	// The index of a newer version is rejected
+	newer := *idx
+	newer.FormatVersion = IndexFormatVersion + 1
+	b, err = json.Marshal(newer)
+	require.NoError(t, err)
+	_, err = UnmarshalIndex(b)
+	require.ErrorContains(t, err, "is newer than the supported version")
+}
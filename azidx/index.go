@@ -1,6 +1,7 @@
 package azidx
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -20,6 +21,7 @@ import (
 	"github.com/go-openapi/jsonpointer"
 	"github.com/go-openapi/jsonreference"
 	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
 	"github.com/magodo/armid"
 	"github.com/magodo/workerpool"
 )
@@ -30,8 +32,17 @@ var defaultDedup []byte
 type FlattenOpIndex map[OpLocator]OperationRefs
 
 type Index struct {
+	// FormatVersion is the version of the index format, see IndexFormatVersion.
+	FormatVersion     int    `json:"format_version,omitempty"`
 	Commit            string `json:"commit,omitempty"`
 	ResourceProviders `json:"resource_providers"`
+	// Operations is the metadata of the operations, keyed by the operation ref.
+	// The path and the names in the original casing are always recorded, while the others (e.g. operationId, long running) are only built on demand (see BuildOptions.OperationMetadata).
+	// This is absent for the index built by an older version of this tool.
+	Operations map[string]*OperationMetadata `json:"operations,omitempty"`
+
+	// matchers are the lookup matchers built once by the Load* functions, see buildLookupMatchers.
+	matchers *lookupMatchers
 }
 
 type ResourceProviders map[string]APIVersions
@@ -91,12 +102,54 @@ func (o *OperationRefs) UnmarshalJSON(b []byte) error {
 		if err != nil {
 			return err
 		}
-		refs[PathPatternStr(k)] = jsonreference.MustCreateRef(v)
+		ref, err := jsonreference.New(v)
+		if err != nil {
+			return fmt.Errorf("parsing ref %q: %v", v, err)
+		}
+		refs[PathPatternStr(k)] = ref
 	}
 	*o = refs
 	return nil
 }
 
+// OperationMetadata is the metadata of an operation, which is not needed by the lookup, but saves the consumers from loading the Swagger spec.
+type OperationMetadata struct {
+	// Path is the API path as defined in the Swagger, e.g. /subscriptions/{subscriptionId}/providers/Microsoft.Compute/virtualMachines/{vmName}
+	Path        string `json:"path"`
+	OperationID string `json:"operation_id,omitempty"`
+	Deprecated  bool   `json:"deprecated,omitempty"`
+	// LongRunning indicates the x-ms-long-running-operation is true.
+	LongRunning bool `json:"long_running,omitempty"`
+	// FinalStateVia is the final-state-via of the x-ms-long-running-operation-options, if any.
+	FinalStateVia string `json:"final_state_via,omitempty"`
+	// Pageable indicates the x-ms-pageable is defined.
+	Pageable bool `json:"pageable,omitempty"`
+	// NextLinkName is the nextLinkName of the x-ms-pageable. It is empty if the operation is not pageable, or has only one page.
+	NextLinkName string `json:"next_link_name,omitempty"`
+	// PathPatterns maps each path pattern of this operation in the index to the names in the original casing of the Swagger.
+	// There can be more than one path patterns if the path contains enum parameters.
+	PathPatterns map[PathPatternStr]OperationNames `json:"path_patterns"`
+	// NamesOnly indicates only the path and the names in the original casing are recorded, and the others are absent (see BuildOptions.OperationMetadata).
+	NamesOnly bool `json:"names_only,omitempty"`
+}
+
+// OperationNames are the names of an operation in the original casing of the Swagger, in correspondence to the upper cased OpLocator and PathPatternStr.
+type OperationNames struct {
+	// RP name, e.g. Microsoft.Compute. This can be "*" for the wildcard RP.
+	RP string `json:"rp"`
+	// Resource type, e.g. /virtualMachines/extensions. Each subtype can be "*".
+	RT string `json:"rt"`
+	// Action/collection type, e.g. listKeys. This can be "*".
+	ACT string `json:"act,omitempty"`
+	// Path pattern, e.g. /subscriptions/{}/providers/Microsoft.Compute/virtualMachines/{}
+	PathPattern string `json:"path_pattern"`
+}
+
+// ResourceType returns the fully qualified resource type, e.g. Microsoft.Compute/virtualMachines/extensions.
+func (n OperationNames) ResourceType() string {
+	return strings.TrimSuffix(n.RP+n.RT, "/")
+}
+
 // PathPatternStr represents an API path pattern, with all the fixed segment upper cased, and all the parameterized segment as a literal "{}", or "{*}" (for x-ms-skip-url-encoding).
 type PathPatternStr string
 
@@ -105,6 +158,44 @@ type PathPatternStr string
 // Optionally specify a deduplication file. Otherwise, it will use a default dedup file instead.
 // Optionally specify a list of services (e.g. `compute`) to build. Otherwise, it will generate for every service.
 func BuildIndex(specdir string, dedupFile string, services []string) (*Index, error) {
+	return BuildIndexWithOptions(context.Background(), BuildOptions{
+		SpecDir:   specdir,
+		DedupFile: dedupFile,
+		Services:  services,
+	})
+}
+
+// BuildOptions are the options of BuildIndexWithOptions.
+type BuildOptions struct {
+	// SpecDir is the specification directory, e.g. /path/to/azure-rest-api-specs/specification
+	SpecDir string
+	// DedupFile is the optional deduplication file. Otherwise, the default dedup file is used.
+	DedupFile string
+	// Services is the optional list of services (e.g. `compute`) to build. Otherwise, every service is built.
+	Services []string
+	// Workers is the count of the spec files parsed concurrently. Defaults to runtime.NumCPU() if not positive.
+	Workers int
+	// Progress is the optional callback that is called after each spec file is parsed. It is never called concurrently.
+	Progress func(BuildProgress)
+	// OperationMetadata records the full operation metadata (see Index.Operations) in the index, e.g. operationId, long running, pageable and deprecated, which makes the index larger.
+	// Defaults to the compact index with only the path and the names in the original casing.
+	OperationMetadata bool
+}
+
+// BuildProgress is the progress of parsing the spec files.
+type BuildProgress struct {
+	// Parsed is the count of the spec files that have been parsed.
+	Parsed int
+	// Total is the count of the spec files to parse.
+	Total int
+	// Spec is the path of the spec file just parsed, relative to the specification directory.
+	Spec string
+}
+
+// BuildIndexWithOptions builds the index as BuildIndex, with the concurrency limited and the progress reported by the options.
+// It stops once the context is done, and returns the context error.
+func BuildIndexWithOptions(ctx context.Context, opts BuildOptions) (*Index, error) {
+	specdir, dedupFile, services := opts.SpecDir, opts.DedupFile, opts.Services
 	specdir, err := filepath.Abs(specdir)
 	if err != nil {
 		return nil, err
@@ -148,8 +239,11 @@ func BuildIndex(specdir string, dedupFile string, services []string) (*Index, er
 	logger.Info(fmt.Sprintf("%d specs collected", len(l)))
 
 	logger.Info("Building operation index")
-	ops, err := buildOpsIndex(specdir, deduplicator, l)
+	ops, metas, err := buildOpsIndex(ctx, specdir, deduplicator, l, opts.Workers, opts.OperationMetadata, opts.Progress)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		return nil, fmt.Errorf("building operation index: %v", err)
 	}
 
@@ -190,13 +284,54 @@ func BuildIndex(specdir string, dedupFile string, services []string) (*Index, er
 	}
 
 	index := &Index{
+		FormatVersion:     IndexFormatVersion,
 		Commit:            commit,
 		ResourceProviders: rps,
 	}
+	index.attachOperationMetadata(metas)
 
 	return index, nil
 }
 
+// attachOperationMetadata sets the operation metadata of the index, only for the operations (and path patterns) that exist in the index,
+// i.e. survive the deduplication.
+func (idx *Index) attachOperationMetadata(metas map[string]*OperationMetadata) {
+	operations := map[string]*OperationMetadata{}
+	add := func(oprefs OperationRefs) {
+		for ppattern, ref := range oprefs {
+			meta, ok := metas[ref.String()]
+			if !ok {
+				continue
+			}
+			names, ok := meta.PathPatterns[ppattern]
+			if !ok {
+				continue
+			}
+			pmeta, ok := operations[ref.String()]
+			if !ok {
+				m := *meta
+				m.PathPatterns = map[PathPatternStr]OperationNames{}
+				pmeta = &m
+				operations[ref.String()] = pmeta
+			}
+			pmeta.PathPatterns[ppattern] = names
+		}
+	}
+	for _, versions := range idx.ResourceProviders {
+		for _, methods := range versions {
+			for _, rts := range methods {
+				for _, info := range rts {
+					add(info.OperationRefs)
+					for _, oprefs := range info.Actions {
+						add(oprefs)
+					}
+				}
+			}
+		}
+	}
+	idx.Operations = operations
+}
+
 // collectSpecs collects all Swagger specs based on the effective tags in each RP's readme.md.
 // If services is not nil, it will only collect specs for the specified services.
 func collectSpecs(rootdir string, services []string) ([]string, error) {
@@ -264,12 +399,13 @@ func collectSpecs(rootdir string, services []string) ([]string, error) {
 	return speclist, nil
 }
 
-func buildOpsIndex(specdir string, deduplicator Deduplicator, specs []string) (FlattenOpIndex, error) {
+func buildOpsIndex(ctx context.Context, specdir string, deduplicator Deduplicator, specs []string, workers int, fullMetadata bool, progress func(BuildProgress)) (FlattenOpIndex, map[string]*OperationMetadata, error) {
 	specdir, err := filepath.Abs(specdir)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	ops := FlattenOpIndex{}
+	metas := map[string]*OperationMetadata{}
 	var lock sync.Mutex
 
 	type dupkey struct {
@@ -278,12 +414,23 @@ func buildOpsIndex(specdir string, deduplicator Deduplicator, specs []string) (F
 	}
 	dups := map[dupkey][]jsonreference.Ref{}
 
-	wp := workerpool.NewWorkPool(runtime.NumCPU())
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	var parsed int
+
+	wp := workerpool.NewWorkPool(workers)
 	wp.Run(nil)
 	for _, spec := range specs {
+		if ctx.Err() != nil {
+			break
+		}
 		spec := spec
 		wp.AddTask(func() (interface{}, error) {
-			m, err := parseSpec(specdir, spec)
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			m, mmetas, err := parseSpec(specdir, spec, fullMetadata)
 			if err != nil {
 				return nil, fmt.Errorf("parsing spec %s: %v", spec, err)
 			}
@@ -291,6 +438,26 @@ func buildOpsIndex(specdir string, deduplicator Deduplicator, specs []string) (F
 			lock.Lock()
 			defer lock.Unlock()
 
+			parsed++
+			if progress != nil {
+				relSpec, err := filepath.Rel(specdir, spec)
+				if err != nil {
+					relSpec = spec
+				}
+				progress(BuildProgress{Parsed: parsed, Total: len(specs), Spec: relSpec})
+			}
+
+			for ref, meta := range mmetas {
+				exist, ok := metas[ref]
+				if !ok {
+					metas[ref] = meta
+					continue
+				}
+				for ppattern, names := range meta.PathPatterns {
+					exist.PathPatterns[ppattern] = names
+				}
+			}
+
 			for k, mm := range m {
 				if len(ops[k]) == 0 {
 					ops[k] = OperationRefs{}
@@ -315,7 +482,10 @@ func buildOpsIndex(specdir string, deduplicator Deduplicator, specs []string) (F
 		})
 	}
 	if err := wp.Done(); err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
 	}
 
 	// Resolve duplicates (auto)
@@ -330,7 +500,7 @@ func buildOpsIndex(specdir string, deduplicator Deduplicator, specs []string) (F
 		for _, ref := range refs {
 			pinfo, err := specpath.SpecPathInfo(ref.GetURL().Path)
 			if err != nil {
-				return nil, fmt.Errorf("new spec path info: %v", err)
+				return nil, nil, fmt.Errorf("new spec path info: %v", err)
 			}
 			// Only pick up the op locator that well matches its spec path, which hopefully is the orignal spec that defines this operation
 			if strings.EqualFold(pinfo.ResourceProviderMS, k.RP) &&
@@ -367,7 +537,7 @@ func buildOpsIndex(specdir string, deduplicator Deduplicator, specs []string) (F
 			op := op
 			if matcher.Match(k.OpLocator, string(k.PathPatternStr)) {
 				if dedupOp != nil {
-					return nil, fmt.Errorf("Duplicate matchers in duplicator that match %s: %s vs %s", k, matcherName, matcher.Name)
+					return nil, nil, fmt.Errorf("Duplicate matchers in duplicator that match %s: %s vs %s", k, matcherName, matcher.Name)
 				}
 				dedupOp = &op
 				matcherName = matcher.Name
@@ -419,44 +589,45 @@ func buildOpsIndex(specdir string, deduplicator Deduplicator, specs []string) (F
 		logger.Warn("duplicate definition", "oploc", k.OpLocator, "path", k.PathPatternStr, "refs", refMsg)
 	}
 
-	return ops, nil
+	return ops, metas, nil
 }
 
-// parseSpec parses one Swagger spec and returns back a operation index for this spec
-func parseSpec(specdir, p string) (FlattenOpIndex, error) {
+// parseSpec parses one Swagger spec and returns back a operation index for this spec, together with the operation metadata keyed by the operation ref
+func parseSpec(specdir, p string, fullMetadata bool) (FlattenOpIndex, map[string]*OperationMetadata, error) {
 	doc, err := loads.Spec(p)
 	if err != nil {
-		return nil, fmt.Errorf("loading spec: %v", err)
+		return nil, nil, fmt.Errorf("loading spec: %v", err)
 	}
 	swagger := doc.Spec()
 
 	// Skipping swagger specs that have no "paths" defined
 	if swagger.Paths == nil || len(swagger.Paths.Paths) == 0 {
-		return nil, nil
+		return nil, nil, nil
 	}
 	if swagger.Info == nil {
-		return nil, fmt.Errorf(`spec has no "Info"`)
+		return nil, nil, fmt.Errorf(`spec has no "Info"`)
 	}
 	if swagger.Info.Version == "" {
-		return nil, fmt.Errorf(`spec has no "Info.Version"`)
+		return nil, nil, fmt.Errorf(`spec has no "Info.Version"`)
 	}
 
 	absSpecPath, err := filepath.Abs(p)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get abs path for %s: %v", p, err)
+		return nil, nil, fmt.Errorf("failed to get abs path for %s: %v", p, err)
 	}
 	relSpecPath, err := filepath.Rel(specdir, absSpecPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get rel path for %s: %v", p, err)
+		return nil, nil, fmt.Errorf("failed to get rel path for %s: %v", p, err)
 	}
 
 	pinfo, err := specpath.SpecPathInfo(relSpecPath)
 	if err != nil {
-		return nil, fmt.Errorf("new spec path info: %v", err)
+		return nil, nil, fmt.Errorf("new spec path info: %v", err)
 	}
 
 	version := swagger.Info.Version
 	index := FlattenOpIndex{}
+	metas := map[string]*OperationMetadata{}
 	for path, pathItem := range swagger.Paths.Paths {
 		for _, opKind := range PossibleOperationKinds {
 			if PathItemOperation(pathItem, opKind) == nil {
@@ -465,7 +636,7 @@ func parseSpec(specdir, p string) (FlattenOpIndex, error) {
 			logger.Debug("Parsing spec", "spec", p, "path", path, "operation", opKind)
 			pathPatterns, err := ParsePathPatternFromSwagger(p, swagger, path, opKind)
 			if err != nil {
-				return nil, fmt.Errorf("parsing path pattern for %s (%s): %v", path, opKind, err)
+				return nil, nil, fmt.Errorf("parsing path pattern for %s (%s): %v", path, opKind, err)
 			}
 			for _, pathPattern := range pathPatterns {
 				// path -> RP, RT, ACT
@@ -555,10 +726,25 @@ func parseSpec(specdir, p string) (FlattenOpIndex, error) {
 					Method:  opKind,
 				}
 
+				names := OperationNames{
+					RP:          rp,
+					RT:          rt,
+					ACT:         act,
+					PathPattern: pathPattern.String(),
+				}
+
 				if rpIsGlob {
 					opLoc.RP = Wildcard
+					names.RP = Wildcard
 				}
 
+				meta, ok := metas[opRef.String()]
+				if !ok {
+					meta = newOperationMetadata(path, PathItemOperation(pathItem, opKind), fullMetadata)
+					metas[opRef.String()] = meta
+				}
+				meta.PathPatterns[pathPatternStr] = names
+
 				if _, ok := index[opLoc]; !ok {
 					index[opLoc] = map[PathPatternStr]jsonreference.Ref{}
 				}
@@ -569,100 +755,235 @@ func parseSpec(specdir, p string) (FlattenOpIndex, error) {
 			}
 		}
 	}
-	return index, nil
+	return index, metas, nil
 }
 
+func newOperationMetadata(path string, op *spec.Operation, full bool) *OperationMetadata {
+	meta := &OperationMetadata{
+		Path:         path,
+		PathPatterns: map[PathPatternStr]OperationNames{},
+	}
+	if !full {
+		meta.NamesOnly = true
+		return meta
+	}
+	meta.OperationID = op.ID
+	meta.Deprecated = op.Deprecated
+	if v, ok := op.Extensions.GetBool("x-ms-long-running-operation"); ok {
+		meta.LongRunning = v
+	}
+	if v, ok := op.Extensions["x-ms-long-running-operation-options"]; ok {
+		if m, ok := v.(map[string]interface{}); ok {
+			if v, ok := m["final-state-via"].(string); ok {
+				meta.FinalStateVia = v
+			}
+		}
+	}
+	pageable, err := parsePageable(op.Extensions)
+	if err != nil {
+		logger.Warn("invalid x-ms-pageable", "path", path, "operation", op.ID, "error", err)
+	}
+	if pageable != nil {
+		meta.Pageable = true
+		meta.NextLinkName = pageable.NextLinkName
+	}
+	return meta
+}
+
+// LookupResult is the result of looking up a request in the index.
+type LookupResult struct {
+	// OpLocator is the locator of the matched operation in the index, where the RP, RT and ACT can be "*".
+	OpLocator
+	// PathPattern is the matched path pattern.
+	PathPattern PathPatternStr
+	// Names are the names of the matched operation in the original casing of the Swagger.
+	// This is nil if the index has no operation metadata.
+	Names *OperationNames
+	// Metadata is the metadata of the matched operation.
+	// This is nil if the index has no operation metadata.
+	Metadata *OperationMetadata
+	// Ref is the JSON reference to the matched operation.
+	Ref jsonreference.Ref
+	// LROPoll is set if the request is the GET of an LRO polling URL. The match might be only by the wildcards (i.e. RP is "*"),
+	// as the polling URLs often have no explicit definition.
+	LROPoll *LROPoll
+}
+
+// Lookup looks up the request in the index and returns the JSON reference to the matched operation.
+// If the request matches nothing, the error is a *LookupError, see LookupOperation.
 func (idx Index) Lookup(method string, uRL url.URL) (*jsonreference.Ref, error) {
+	result, err := idx.LookupOperation(method, uRL)
+	if err != nil {
+		return nil, err
+	}
+	return &result.Ref, nil
+}
+
+// LookupOperation looks up the request in the index and returns the matched operation.
+// If the request matches nothing, the error is a *LookupError, which can be tested against the sentinel errors (e.g. ErrUnknownProvider) via errors.Is.
+func (idx Index) LookupOperation(method string, uRL url.URL) (*LookupResult, error) {
+	result, _, err := idx.lookupOperation(method, uRL)
+	return result, err
+}
+
+// ClassifyLookupFailure looks up the request in the index and returns the cause if it matches nothing. Otherwise, it returns "".
+func (idx Index) ClassifyLookupFailure(method string, uRL url.URL) LookupFailureCause {
+	_, cause, _ := idx.lookupOperation(method, uRL)
+	return cause
+}
+
+// lookupOperation looks up the request in the index. If it matches nothing, the cause is returned together with the error.
+// The GET request of an LRO polling URL (see ParseLROPoll) is classified via LookupResult.LROPoll, or LookupError.LROPoll if it matches nothing,
+// without changing the outcome of the lookup.
+func (idx Index) lookupOperation(method string, uRL url.URL) (*LookupResult, LookupFailureCause, error) {
+	result, cause, err := idx.matchOperation(method, uRL)
+	if !strings.EqualFold(method, string(OperationKindGet)) {
+		return result, cause, err
+	}
+	poll := ParseLROPoll(uRL)
+	if poll == nil {
+		return result, cause, err
+	}
+	if err == nil {
+		result.LROPoll = poll
+	} else if lerr, ok := err.(*LookupError); ok {
+		lerr.LROPoll = poll
+	}
+	return result, cause, err
+}
+
+// matchOperation matches the request against the operations in the index. If it matches nothing, the cause is returned together with the error.
+func (idx Index) matchOperation(method string, uRL url.URL) (*LookupResult, LookupFailureCause, error) {
 	operation := OperationKind(strings.ToUpper(method))
 	apiVersion := uRL.Query().Get("api-version")
 
-	path := strings.TrimRight(strings.ToUpper(uRL.Path), "/")
+	path, rp, rt, act, err := parseRequestPath(uRL)
+	if err != nil {
+		return nil, LookupFailureInvalidResourceID, &LookupError{
+			Method:     method,
+			URL:        uRL.String(),
+			APIVersion: apiVersion,
+			Cause:      LookupFailureInvalidResourceID,
+			Err:        err,
+		}
+	}
+
+	cause := LookupFailureUnknownRP
+	rpInfo, rpKnown := idx.ResourceProviders[rp]
+	if rpKnown {
+		result, rpCause, err := lookupIntoRP(idx.matchers, rp, rpInfo, path, apiVersion, operation, rt, act)
+		if err != nil {
+			return nil, "", fmt.Errorf("lookup for %v (%s) in rp %s: %v", uRL.String(), method, rp, err)
+		}
+		if result != nil {
+			result.RP = rp
+			result.Metadata, result.Names = idx.operationMetadata(result.Ref, result.PathPattern)
+			return result, "", nil
+		}
+		cause = rpCause
+	}
+	result, wildcardCause, err := lookupIntoRP(idx.matchers, Wildcard, idx.ResourceProviders[Wildcard], path, apiVersion, operation, rt, act)
+	if err != nil {
+		return nil, "", fmt.Errorf("lookup for %v (%s) in the wildcard rp: %v", uRL.String(), method, err)
+	}
+	if result == nil {
+		// For a known RP, the cause is the furthest step reached by looking up in either the RP or the wildcard RP.
+		if rpKnown && wildcardCause.rank() > cause.rank() {
+			cause = wildcardCause
+		}
+		return nil, cause, &LookupError{
+			Method:     method,
+			URL:        uRL.String(),
+			RP:         rp,
+			RT:         rt,
+			ACT:        act,
+			APIVersion: apiVersion,
+			Cause:      cause,
+		}
+	}
+	result.RP = Wildcard
+	result.Metadata, result.Names = idx.operationMetadata(result.Ref, result.PathPattern)
+	return result, "", nil
+}
+
+// parseRequestPath parses the path of the request URL, and returns the upper cased path, RP, RT and ACT.
+func parseRequestPath(uRL url.URL) (path, rp, rt, act string, err error) {
+	path = strings.TrimRight(strings.ToUpper(uRL.Path), "/")
 	segs := strings.Split(strings.TrimLeft(path, "/"), "/")
 
 	respath := path
-	var act string
 	if len(segs)%2 == 1 {
 		act = strings.ToUpper(segs[len(segs)-1])
 		respath = "/" + strings.Join(segs[:len(segs)-1], "/")
 	}
 	id, err := armid.ParseResourceId(respath)
 	if err != nil {
-		return nil, fmt.Errorf("parsing %s as arm id: %v", respath, err)
+		return "", "", "", "", fmt.Errorf("parsing %s as arm id: %v", respath, err)
 	}
 
-	rp := strings.ToUpper(id.Provider())
-	rt := strings.ToUpper("/" + strings.Join(id.Types(), "/"))
+	rp = strings.ToUpper(id.Provider())
+	rt = strings.ToUpper("/" + strings.Join(id.Types(), "/"))
+	return path, rp, rt, act, nil
+}
 
-	if rpInfo, ok := idx.ResourceProviders[rp]; ok {
-		ref, ok, err := lookupIntoRP(rpInfo, path, apiVersion, operation, rt, act)
-		if err != nil {
-			return nil, fmt.Errorf("lookup for %v (%s) in rp %s: %v", uRL.String(), method, rp, err)
-		}
-		if ok {
-			return ref, nil
-		}
+// operationMetadata returns the metadata of the operation, and its names of the path pattern in the original casing, or nil if there is no such metadata.
+// The metadata is nil if it only records the names, i.e. the index is not built with BuildOptions.OperationMetadata.
+func (idx Index) operationMetadata(ref jsonreference.Ref, ppattern PathPatternStr) (*OperationMetadata, *OperationNames) {
+	meta, ok := idx.Operations[ref.String()]
+	if !ok {
+		return nil, nil
 	}
-	ref, ok, err := lookupIntoRP(idx.ResourceProviders[Wildcard], path, apiVersion, operation, rt, act)
-	if err != nil {
-		return nil, fmt.Errorf("lookup for %v (%s) in the wildcard rp: %v", uRL.String(), method, err)
+	var pnames *OperationNames
+	if names, ok := meta.PathPatterns[ppattern]; ok {
+		pnames = &names
 	}
-	if !ok {
-		return nil, fmt.Errorf("lookup for %v (%s): matches nothing", uRL.String(), method)
+	if meta.NamesOnly {
+		return nil, pnames
 	}
-	return ref, nil
+	return meta, pnames
 }
 
-func lookupIntoRP(rpInfo map[string]APIMethods, path, apiVersion string, operation OperationKind, rt, act string) (*jsonreference.Ref, bool, error) {
-	rpVer, ok := rpInfo[apiVersion]
-	if !ok {
-		return nil, false, nil
+func buildRTMatcher(rt string) Matcher {
+	segs := strings.Split(strings.Trim(rt, "/"), "/")
+	m := Matcher{
+		PrefixSep: true,
+		Separater: "/",
 	}
-	rpVerOp, ok := rpVer[operation]
-	if !ok {
-		return nil, false, nil
-	}
-
-	buildRTMatcher := func(rt string) Matcher {
-		segs := strings.Split(strings.Trim(rt, "/"), "/")
-		m := Matcher{
-			PrefixSep: true,
-			Separater: "/",
-		}
-		for _, seg := range segs {
-			if seg == "*" {
-				m.Segments = append(m.Segments, MatchSegment{IsWildcard: true})
-				continue
-			}
-			m.Segments = append(m.Segments, MatchSegment{Value: seg})
+	for _, seg := range segs {
+		if seg == "*" {
+			m.Segments = append(m.Segments, MatchSegment{IsWildcard: true})
+			continue
 		}
-		return m
+		m.Segments = append(m.Segments, MatchSegment{Value: seg})
 	}
+	return m
+}
 
-	type opInfoWrapper struct {
-		info      OperationInfo
-		rtMatcher Matcher
+// lookupIntoRP looks up the request in one RP. The returned result has no RP set.
+// If it matches nothing, the returned result is nil, and the cause is returned.
+// The matchers are the prebuilt lookup matchers of the index, which can be nil.
+func lookupIntoRP(matchers *lookupMatchers, rp string, rpInfo map[string]APIMethods, path, apiVersion string, operation OperationKind, rt, act string) (*LookupResult, LookupFailureCause, error) {
+	rpVer, ok := rpInfo[apiVersion]
+	if !ok {
+		return nil, LookupFailureAPIVersionNotFound, nil
 	}
-
-	var opInfoWrappers []opInfoWrapper
-	for rt, opInfo := range rpVerOp {
-		opInfoWrappers = append(opInfoWrappers, opInfoWrapper{
-			info:      *opInfo,
-			rtMatcher: buildRTMatcher(rt),
-		})
+	rpVerOp, ok := rpVer[operation]
+	if !ok {
+		return nil, LookupFailureMethodNotFound, nil
 	}
+	cause := LookupFailureRTUnmatched
 
-	// Sort the resource type matchers to match from the most specific to the most general
-	sort.Slice(opInfoWrappers, func(i, j int) bool {
-		return opInfoWrappers[i].rtMatcher.Less(opInfoWrappers[j].rtMatcher)
-	})
-
-	for _, opInfoWrapper := range opInfoWrappers {
-		if !opInfoWrapper.rtMatcher.Match(rt) {
+	for _, rtm := range matchers.rtMatchers(rp, apiVersion, operation, rpVerOp) {
+		if !rtm.matcher.Match(rt) {
 			continue
 		}
-		opInfo := opInfoWrapper.info
+		opInfo := rtm.info
 		oprefs := opInfo.OperationRefs
+		actKey := act
 		if act != "" {
+			if cause.rank() < LookupFailureActionUnmatched.rank() {
+				cause = LookupFailureActionUnmatched
+			}
 			if len(opInfo.Actions) == 0 {
 				continue
 			}
@@ -673,47 +994,29 @@ func lookupIntoRP(rpInfo map[string]APIMethods, path, apiVersion string, operati
 				if !ok {
 					continue
 				}
+				actKey = Wildcard
 			}
 		}
+		cause = LookupFailurePathPatternUnmatched
 
 		// Select the best matching path from candidate paths
-		type opRefWrapper struct {
-			ref         jsonreference.Ref
-			pathMatcher Matcher
-		}
-		var opRefWrappers []opRefWrapper
-		for ppath, ref := range oprefs {
-			pathPattern := ParsePathPatternFromString(string(ppath))
-			m := Matcher{
-				PrefixSep: true,
-				Separater: "/",
-			}
-			for _, seg := range pathPattern.Segments {
-				m.Segments = append(m.Segments, MatchSegment{
-					Value:      seg.FixedName,
-					IsWildcard: seg.IsParameter,
-					IsAny:      seg.IsMulti,
-				})
-			}
-			opRefWrappers = append(opRefWrappers, opRefWrapper{
-				ref:         ref,
-				pathMatcher: m,
-			})
-		}
-
-		// Sort the path matchers to match from the most specific to the most general
-		sort.Slice(opRefWrappers, func(i, j int) bool {
-			return opRefWrappers[i].pathMatcher.Less(opRefWrappers[j].pathMatcher)
-		})
-
-		for _, opRefWrapper := range opRefWrappers {
-			if opRefWrapper.pathMatcher.Match(path) {
-				return &opRefWrapper.ref, true, nil
+		for _, pm := range matchers.pathMatchers(opInfo, actKey, oprefs) {
+			if pm.matcher.Match(path) {
+				return &LookupResult{
+					OpLocator: OpLocator{
+						Version: apiVersion,
+						RT:      rtm.rt,
+						ACT:     actKey,
+						Method:  operation,
+					},
+					PathPattern: pm.ppath,
+					Ref:         pm.ref,
+				}, "", nil
 			}
 		}
 	}
 
-	return nil, false, nil
+	return nil, cause, nil
 }
 
 func allParameterized(segs []PathSegment) bool {
@@ -1,9 +1,11 @@
 package azidx
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"regexp"
 	"testing"
 
@@ -13,11 +15,12 @@ import (
 
 func TestBuildIndex(t *testing.T) {
 	specRoot := "../testdata/spec"
-	idx, err := BuildIndex(specRoot, "", nil)
+	idx, err := BuildIndexWithOptions(context.Background(), BuildOptions{SpecDir: specRoot, OperationMetadata: true})
 	require.NoError(t, err)
 	b, err := json.MarshalIndent(idx, "", "  ")
 	require.NoError(t, err)
 	expected := fmt.Sprintf(`{
+  "format_version": 2,
   "resource_providers": {
     "MICROSOFT.DUMMY": {
       "2023-05-01-preview": {
@@ -79,9 +82,128 @@ func TestBuildIndex(t *testing.T) {
         }
       }
     }
+  },
+  "operations": {
+    "dummy/resource-manager/Microsoft.Dummy/preview/2023-05-01-preview/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1%%7BfooName%%7D/delete": {
+      "path": "/providers/Microsoft.Dummy/foos/{fooName}",
+      "path_patterns": {
+        "/PROVIDERS/MICROSOFT.DUMMY/FOOS/{}": {
+          "rp": "Microsoft.Dummy",
+          "rt": "/foos",
+          "path_pattern": "/providers/Microsoft.Dummy/foos/{}"
+        }
+      }
+    },
+    "dummy/resource-manager/Microsoft.Dummy/preview/2023-05-01-preview/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1%%7BfooName%%7D/get": {
+      "path": "/providers/Microsoft.Dummy/foos/{fooName}",
+      "path_patterns": {
+        "/PROVIDERS/MICROSOFT.DUMMY/FOOS/{}": {
+          "rp": "Microsoft.Dummy",
+          "rt": "/foos",
+          "path_pattern": "/providers/Microsoft.Dummy/foos/{}"
+        }
+      }
+    },
+    "dummy/resource-manager/Microsoft.Dummy/preview/2023-05-01-preview/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1%%7BfooName%%7D/put": {
+      "path": "/providers/Microsoft.Dummy/foos/{fooName}",
+      "path_patterns": {
+        "/PROVIDERS/MICROSOFT.DUMMY/FOOS/{}": {
+          "rp": "Microsoft.Dummy",
+          "rt": "/foos",
+          "path_pattern": "/providers/Microsoft.Dummy/foos/{}"
+        }
+      }
+    },
+    "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos/get": {
+      "path": "/providers/Microsoft.Dummy/foos",
+      "operation_id": "Foos_List",
+      "pageable": true,
+      "next_link_name": "nextLink",
+      "path_patterns": {
+        "/PROVIDERS/MICROSOFT.DUMMY/FOOS": {
+          "rp": "Microsoft.Dummy",
+          "rt": "/",
+          "act": "foos",
+          "path_pattern": "/providers/Microsoft.Dummy/foos"
+        }
+      }
+    },
+    "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1%%7BfooName%%7D/delete": {
+      "path": "/providers/Microsoft.Dummy/foos/{fooName}",
+      "operation_id": "Foos_Delete",
+      "deprecated": true,
+      "path_patterns": {
+        "/PROVIDERS/MICROSOFT.DUMMY/FOOS/{}": {
+          "rp": "Microsoft.Dummy",
+          "rt": "/foos",
+          "path_pattern": "/providers/Microsoft.Dummy/foos/{}"
+        }
+      }
+    },
+    "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1%%7BfooName%%7D/get": {
+      "path": "/providers/Microsoft.Dummy/foos/{fooName}",
+      "operation_id": "Foos_Get",
+      "path_patterns": {
+        "/PROVIDERS/MICROSOFT.DUMMY/FOOS/{}": {
+          "rp": "Microsoft.Dummy",
+          "rt": "/foos",
+          "path_pattern": "/providers/Microsoft.Dummy/foos/{}"
+        }
+      }
+    },
+    "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1%%7BfooName%%7D/put": {
+      "path": "/providers/Microsoft.Dummy/foos/{fooName}",
+      "operation_id": "Foos_CreateOrUpdate",
+      "long_running": true,
+      "final_state_via": "azure-async-operation",
+      "path_patterns": {
+        "/PROVIDERS/MICROSOFT.DUMMY/FOOS/{}": {
+          "rp": "Microsoft.Dummy",
+          "rt": "/foos",
+          "path_pattern": "/providers/Microsoft.Dummy/foos/{}"
+        }
+      }
+    },
+    "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1%%7BfooName%%7D~1bars~1%%7BbarName%%7D/get": {
+      "path": "/providers/Microsoft.Dummy/foos/{fooName}/bars/{barName}",
+      "operation_id": "Bars_Get",
+      "path_patterns": {
+        "/PROVIDERS/MICROSOFT.DUMMY/FOOS/{}/BARS/{}": {
+          "rp": "Microsoft.Dummy",
+          "rt": "/foos/bars",
+          "path_pattern": "/providers/Microsoft.Dummy/foos/{}/bars/{}"
+        }
+      }
+    }
   }
 }`)
 	require.Equal(t, expected, string(b))
+
+	// The full operation metadata is only built on demand, while the original casing is always recorded
+	idx, err = BuildIndex(specRoot, "", nil)
+	require.NoError(t, err)
+	require.Len(t, idx.Operations, 8)
+	for _, meta := range idx.Operations {
+		require.NotEmpty(t, meta.Path)
+		require.NotEmpty(t, meta.PathPatterns)
+		require.True(t, meta.NamesOnly)
+		require.Empty(t, meta.OperationID)
+		require.False(t, meta.LongRunning)
+		require.False(t, meta.Pageable)
+	}
+	b, err = json.Marshal(idx)
+	require.NoError(t, err)
+	require.NotContains(t, string(b), `"operation_id"`)
+	uRL, err := url.Parse("/providers/microsoft.dummy/foos/foo1/bars/bar1?api-version=2023-05-15")
+	require.NoError(t, err)
+	result, err := idx.LookupOperation("GET", *uRL)
+	require.NoError(t, err)
+	require.Nil(t, result.Metadata)
+	require.Equal(t, &OperationNames{
+		RP:          "Microsoft.Dummy",
+		RT:          "/foos/bars",
+		PathPattern: "/providers/Microsoft.Dummy/foos/{}/bars/{}",
+	}, result.Names)
 }
 
 func TestIndex_Lookup(t *testing.T) {
@@ -231,3 +353,105 @@ func TestIndex_Lookup(t *testing.T) {
 		})
 	}
 }
+
+func TestIndex_LookupOperation(t *testing.T) {
+	index := Index{
+		ResourceProviders: ResourceProviders{
+			"*": APIVersions{
+				"ver1": APIMethods{
+					"GET": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/PROVIDERS/{}/FOOS/{}": jsonreference.MustCreateRef("#*:VER1:GET:/FOOS::P1"),
+							},
+						},
+					},
+				},
+			},
+			"RP1": APIVersions{
+				"ver1": APIMethods{
+					"POST": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							Actions: map[string]OperationRefs{
+								"*": {
+									"/PROVIDERS/RP1/FOOS/{}/{}": jsonreference.MustCreateRef("#RP1:VER1:POST:/FOOS:*:P1"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	uRL, err := url.Parse("/providers/rp0/foos/foo1?api-version=ver1")
+	require.NoError(t, err)
+	result, err := index.LookupOperation("get", *uRL)
+	require.NoError(t, err)
+	require.Equal(t, &LookupResult{
+		OpLocator:   OpLocator{RP: "*", Version: "ver1", RT: "/FOOS", Method: "GET"},
+		PathPattern: "/PROVIDERS/{}/FOOS/{}",
+		Ref:         jsonreference.MustCreateRef("#*:VER1:GET:/FOOS::P1"),
+	}, result)
+
+	uRL, err = url.Parse("/providers/rp1/foos/foo1/sleep?api-version=ver1")
+	require.NoError(t, err)
+	result, err = index.LookupOperation("post", *uRL)
+	require.NoError(t, err)
+	require.Equal(t, &LookupResult{
+		OpLocator:   OpLocator{RP: "RP1", Version: "ver1", RT: "/FOOS", ACT: "*", Method: "POST"},
+		PathPattern: "/PROVIDERS/RP1/FOOS/{}/{}",
+		Ref:         jsonreference.MustCreateRef("#RP1:VER1:POST:/FOOS:*:P1"),
+	}, result)
+
+	// The names in the original casing are returned for the index that has the operation metadata
+	idx, err := BuildIndexWithOptions(context.Background(), BuildOptions{SpecDir: "../testdata/spec", OperationMetadata: true})
+	require.NoError(t, err)
+	b, err := json.Marshal(idx)
+	require.NoError(t, err)
+	var loaded Index
+	require.NoError(t, json.Unmarshal(b, &loaded))
+	uRL, err = url.Parse("/PROVIDERS/microsoft.dummy/FOOS/foo1/bars/bar1?api-version=2023-05-15")
+	require.NoError(t, err)
+	result, err = loaded.LookupOperation("get", *uRL)
+	require.NoError(t, err)
+	require.Equal(t, &OperationNames{
+		RP:          "Microsoft.Dummy",
+		RT:          "/foos/bars",
+		PathPattern: "/providers/Microsoft.Dummy/foos/{}/bars/{}",
+	}, result.Names)
+	require.Equal(t, "Microsoft.Dummy/foos/bars", result.Names.ResourceType())
+	require.Equal(t, "/providers/Microsoft.Dummy/foos/{fooName}/bars/{barName}", result.Metadata.Path)
+	require.Equal(t, "Bars_Get", result.Metadata.OperationID)
+
+	uRL, err = url.Parse("/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15")
+	require.NoError(t, err)
+	result, err = loaded.LookupOperation("put", *uRL)
+	require.NoError(t, err)
+	require.True(t, result.Metadata.LongRunning)
+	require.Equal(t, "azure-async-operation", result.Metadata.FinalStateVia)
+}
+
+func TestBuildIndexWithOptions(t *testing.T) {
+	var progresses []BuildProgress
+	idx, err := BuildIndexWithOptions(context.Background(), BuildOptions{
+		SpecDir: "../testdata/spec",
+		Workers: 1,
+		Progress: func(p BuildProgress) {
+			progresses = append(progresses, p)
+		},
+	})
+	require.NoError(t, err)
+	expect, err := BuildIndex("../testdata/spec", "", nil)
+	require.NoError(t, err)
+	require.Equal(t, expect, idx)
+	require.Equal(t, []BuildProgress{
+		{Parsed: 1, Total: 2, Spec: filepath.Join("dummy", "resource-manager", "Microsoft.Dummy", "preview", "2023-05-01-preview", "foo.json")},
+		{Parsed: 2, Total: 2, Spec: filepath.Join("dummy", "resource-manager", "Microsoft.Dummy", "stable", "2023-05-15", "foo.json")},
+	}, progresses)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = BuildIndexWithOptions(ctx, BuildOptions{SpecDir: "../testdata/spec"})
+	require.ErrorIs(t, err, context.Canceled)
+}
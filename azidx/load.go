@@ -0,0 +1,121 @@
+package azidx
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/go-openapi/jsonpointer"
+	"github.com/go-openapi/jsonreference"
+)
+
+// LoadIndex reads the index in any of the encodings (see DecodeIndex), validates it (see Index.Validate),
+// and builds the lookup matchers once, so that the index is ready for looking up.
+// The loaded index is not expected to be changed.
+func LoadIndex(r io.Reader) (*Index, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := DecodeIndex(b)
+	if err != nil {
+		return nil, err
+	}
+	if err := idx.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid index: %v", err)
+	}
+	idx.buildLookupMatchers()
+	return idx, nil
+}
+
+// LoadIndexFile loads the index file. See LoadIndex.
+func LoadIndexFile(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	idx, err := LoadIndex(f)
+	if err != nil {
+		return nil, fmt.Errorf("loading index file %s: %v", path, err)
+	}
+	return idx, nil
+}
+
+// LoadIndexFS loads the index file from the file system, e.g. an embed.FS. See LoadIndex.
+func LoadIndexFS(fsys fs.FS, name string) (*Index, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	idx, err := LoadIndex(f)
+	if err != nil {
+		return nil, fmt.Errorf("loading index file %s: %v", name, err)
+	}
+	return idx, nil
+}
+
+// Validate checks the index is well formed, i.e. each method is known, each operation info is not nil, and each ref refers to a JSON pointer in a spec file.
+// It returns the first problem found, in the order of the sorted keys.
+func (idx Index) Validate() error {
+	validateRefs := func(oprefs OperationRefs) error {
+		for _, ppattern := range sortedKeys(oprefs) {
+			if err := validateRef(oprefs[ppattern]); err != nil {
+				return fmt.Errorf("path pattern %s: %v", ppattern, err)
+			}
+		}
+		return nil
+	}
+	for _, rp := range sortedKeys(idx.ResourceProviders) {
+		versions := idx.ResourceProviders[rp]
+		for _, version := range sortedKeys(versions) {
+			methods := versions[version]
+			for _, method := range sortedKeys(methods) {
+				loc := fmt.Sprintf("rp %q, version %q, method %q", rp, version, method)
+				if !slices.Contains(PossibleOperationKinds, method) {
+					return fmt.Errorf("%s: unknown method", loc)
+				}
+				rts := methods[method]
+				for _, rt := range sortedKeys(rts) {
+					info := rts[rt]
+					if info == nil {
+						return fmt.Errorf("%s, rt %q: nil operation info", loc, rt)
+					}
+					if err := validateRefs(info.OperationRefs); err != nil {
+						return fmt.Errorf("%s, rt %q: %v", loc, rt, err)
+					}
+					for _, act := range sortedKeys(info.Actions) {
+						if err := validateRefs(info.Actions[act]); err != nil {
+							return fmt.Errorf("%s, rt %q, action %q: %v", loc, rt, act, err)
+						}
+					}
+				}
+			}
+		}
+	}
+	for _, ref := range sortedKeys(idx.Operations) {
+		if idx.Operations[ref] == nil {
+			return fmt.Errorf("operation %s: nil operation metadata", ref)
+		}
+	}
+	return nil
+}
+
+// validateRef checks the ref is in the form of <spec file>#<JSON pointer>.
+func validateRef(ref jsonreference.Ref) error {
+	u := ref.GetURL()
+	if u == nil || u.Path == "" {
+		return fmt.Errorf("ref %q has no spec file", ref.String())
+	}
+	if !strings.HasPrefix(u.Fragment, "/") {
+		return fmt.Errorf("ref %q has no JSON pointer", ref.String())
+	}
+	if _, err := jsonpointer.New(u.Fragment); err != nil {
+		return fmt.Errorf("ref %q has invalid JSON pointer: %v", ref.String(), err)
+	}
+	return nil
+}
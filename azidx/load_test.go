@@ -0,0 +1,72 @@
+package azidx
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/url"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadIndex(t *testing.T) {
+	idx, err := BuildIndex("../testdata/spec", "", nil)
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, EncodeIndex(&buf, idx, IndexEncodingBinary))
+
+	loaded, err := LoadIndexFS(fstest.MapFS{"index.bin": &fstest.MapFile{Data: buf.Bytes()}}, "index.bin")
+	require.NoError(t, err)
+	require.NotNil(t, loaded.matchers)
+	require.Equal(t, idx.ResourceProviders, loaded.ResourceProviders)
+
+	// The lookup with the prebuilt matchers is the same as without
+	uRL, err := url.Parse("/providers/Microsoft.Dummy/foos/foo1/bars/bar1?api-version=2023-05-15")
+	require.NoError(t, err)
+	expect, err := idx.LookupOperation("GET", *uRL)
+	require.NoError(t, err)
+	actual, err := loaded.LookupOperation("GET", *uRL)
+	require.NoError(t, err)
+	require.Equal(t, expect, actual)
+
+	_, err = LoadIndexFS(fstest.MapFS{}, "index.bin")
+	require.Error(t, err)
+}
+
+func TestIndex_Validate(t *testing.T) {
+	newIndex := func(method OperationKind, info *OperationInfo) Index {
+		return Index{
+			ResourceProviders: ResourceProviders{
+				"RP1": APIVersions{
+					"ver1": APIMethods{
+						method: ResourceTypes{"/FOOS": info},
+					},
+				},
+			},
+		}
+	}
+	validInfo := &OperationInfo{
+		OperationRefs: OperationRefs{"/PROVIDERS/RP1/FOOS/{}": jsonreference.MustCreateRef("foo.json#/paths/~1foos~1{name}/get")},
+	}
+
+	require.NoError(t, newIndex("GET", validInfo).Validate())
+	require.ErrorContains(t, newIndex("FETCH", validInfo).Validate(), "unknown method")
+	require.ErrorContains(t, newIndex("GET", nil).Validate(), "nil operation info")
+	require.ErrorContains(t, newIndex("GET", &OperationInfo{
+		Actions: map[string]OperationRefs{
+			"LISTKEYS": {"/PROVIDERS/RP1/FOOS/{}/LISTKEYS": jsonreference.MustCreateRef("#/paths/~1foos~1{name}~1listKeys/post")},
+		},
+	}).Validate(), "has no spec file")
+
+	// The nil operation info is rejected on loading
+	b, err := json.Marshal(newIndex("GET", nil))
+	require.NoError(t, err)
+	_, err = LoadIndex(bytes.NewReader(b))
+	require.ErrorContains(t, err, "nil operation info")
+
+	// The unparsable ref is rejected on unmarshaling, instead of panicking
+	_, err = LoadIndex(bytes.NewReader([]byte(`{"resource_providers": {"RP1": {"ver1": {"GET": {"/FOOS": {"operation_refs": {"/PROVIDERS/RP1/FOOS/{}": "foo.json#%zz"}}}}}}}`)))
+	require.Error(t, err)
+}
@@ -6,6 +6,11 @@ func SetLogger(l Logger) {
 	logger = l
 }
 
+// GetLogger returns the logger set by SetLogger, which is used by the sub packages.
+func GetLogger() Logger {
+	return logger
+}
+
 type Logger interface {
 	Debug(msg string, args ...interface{})
 	Info(msg string, args ...interface{})
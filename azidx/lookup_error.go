@@ -0,0 +1,82 @@
+package azidx
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The sentinel errors of looking up, which can be tested against the returned error via errors.Is.
+// Each lookup failure other than ErrInvalidResourceID is also an ErrNoMatch.
+var (
+	// ErrInvalidResourceID means the request path can't be parsed as an ARM resource id.
+	ErrInvalidResourceID = errors.New("invalid resource id")
+	// ErrUnknownProvider means the RP is not in the index.
+	ErrUnknownProvider = errors.New("unknown resource provider")
+	// ErrAPIVersionNotFound means the RP is known, but the api-version is not.
+	ErrAPIVersionNotFound = errors.New("api version not found")
+	// ErrMethodNotFound means the api-version is known, but has no operation of the method.
+	ErrMethodNotFound = errors.New("method not found")
+	// ErrNoMatch means the request matches no operation in the index.
+	ErrNoMatch = errors.New("matches nothing")
+	// ErrLROPoll means the request that matches nothing is polling a long running operation (see LookupError.LROPoll),
+	// which is usually not regarded as a failure. It is wrapped in addition to the sentinel error of the cause.
+	ErrLROPoll = errors.New("lro polling url")
+)
+
+// LookupError is the error of a request that matches nothing in the index.
+type LookupError struct {
+	// Method is the request method.
+	Method string
+	// URL is the request URL.
+	URL string
+	// RP, RT and ACT are the upper cased names parsed from the request path, which are empty for ErrInvalidResourceID.
+	RP  string
+	RT  string
+	ACT string
+	// APIVersion is the api-version of the request.
+	APIVersion string
+	// Cause is the likely cause of the failure.
+	Cause LookupFailureCause
+	// Err is the underlying error, if any, e.g. the error of parsing the resource id.
+	Err error
+	// LROPoll is set if the request is the GET of an LRO polling URL, see ParseLROPoll.
+	LROPoll *LROPoll
+}
+
+func (e *LookupError) Error() string {
+	msg := "matches nothing"
+	if e.Cause == LookupFailureInvalidResourceID && e.Err != nil {
+		msg = e.Err.Error()
+	}
+	return fmt.Sprintf("lookup for %s (%s): %s", e.URL, e.Method, msg)
+}
+
+// Unwrap returns the sentinel error of the cause, together with the underlying error if any.
+func (e *LookupError) Unwrap() []error {
+	errs := []error{e.Cause.Err()}
+	if e.Cause != LookupFailureInvalidResourceID {
+		errs = append(errs, ErrNoMatch)
+	}
+	if e.LROPoll != nil {
+		errs = append(errs, ErrLROPoll)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// Err returns the sentinel error of the cause. The RT, action and path pattern unmatched causes are all ErrNoMatch.
+func (c LookupFailureCause) Err() error {
+	switch c {
+	case LookupFailureInvalidResourceID:
+		return ErrInvalidResourceID
+	case LookupFailureUnknownRP:
+		return ErrUnknownProvider
+	case LookupFailureAPIVersionNotFound:
+		return ErrAPIVersionNotFound
+	case LookupFailureMethodNotFound:
+		return ErrMethodNotFound
+	}
+	return ErrNoMatch
+}
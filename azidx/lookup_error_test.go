@@ -0,0 +1,76 @@
+package azidx
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookupError(t *testing.T) {
+	index := &Index{
+		ResourceProviders: ResourceProviders{
+			"RP1": APIVersions{
+				"ver1": APIMethods{
+					"GET": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/PROVIDERS/RP1/FOOS/{}": jsonreference.MustCreateRef("#RP1:VER1:GET:/FOOS::P1"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		method string
+		url    string
+		err    error
+	}{
+		{"GET", "/foos/foo1?api-version=ver1", ErrInvalidResourceID},
+		{"GET", "/providers/rp2/bars/bar1?api-version=ver1", ErrUnknownProvider},
+		{"GET", "/providers/rp1/foos/foo1?api-version=ver2", ErrAPIVersionNotFound},
+		{"DELETE", "/providers/rp1/foos/foo1?api-version=ver1", ErrMethodNotFound},
+		{"GET", "/providers/rp1/bars/bar1?api-version=ver1", ErrNoMatch},
+	}
+	for _, tt := range cases {
+		uRL, err := url.Parse(tt.url)
+		require.NoError(t, err)
+		_, err = index.LookupOperation(tt.method, *uRL)
+		require.ErrorIs(t, err, tt.err, "%s %s", tt.method, tt.url)
+		// Each failure other than the invalid resource id also matches nothing
+		require.Equal(t, tt.err != ErrInvalidResourceID, errors.Is(err, ErrNoMatch), "%s %s", tt.method, tt.url)
+
+		var lerr *LookupError
+		require.ErrorAs(t, err, &lerr)
+		require.Equal(t, tt.method, lerr.Method)
+		require.Equal(t, tt.url, lerr.URL)
+		require.Equal(t, tt.err, lerr.Cause.Err())
+	}
+
+	uRL, err := url.Parse("/providers/rp1/foos/foo1?api-version=ver2")
+	require.NoError(t, err)
+	_, err = index.LookupOperation("GET", *uRL)
+	require.EqualError(t, err, "lookup for /providers/rp1/foos/foo1?api-version=ver2 (GET): matches nothing")
+	var lerr *LookupError
+	require.ErrorAs(t, err, &lerr)
+	require.Equal(t, &LookupError{
+		Method:     "GET",
+		URL:        "/providers/rp1/foos/foo1?api-version=ver2",
+		RP:         "RP1",
+		RT:         "/FOOS",
+		APIVersion: "ver2",
+		Cause:      LookupFailureAPIVersionNotFound,
+	}, lerr)
+	require.False(t, errors.Is(err, ErrMethodNotFound))
+
+	// The invalid resource id is reported in the same format, with the parsing error
+	uRL, err = url.Parse("/foos/foo1?api-version=ver1")
+	require.NoError(t, err)
+	_, err = index.LookupOperation("GET", *uRL)
+	require.ErrorContains(t, err, "lookup for /foos/foo1?api-version=ver1 (GET): parsing /FOOS/FOO1 as arm id: ")
+}
@@ -0,0 +1,126 @@
+package azidx
+
+import (
+	"sort"
+
+	"github.com/go-openapi/jsonreference"
+)
+
+// lookupMatchers are the matchers used by looking up, which are sorted from the most specific to the most general.
+// It is built once for an index that won't change afterwards, otherwise, the matchers are built on each lookup.
+// A nil *lookupMatchers is valid, which builds the matchers on demand.
+type lookupMatchers struct {
+	rts   map[rtMatchersKey][]rtMatcher
+	paths map[pathMatchersKey][]pathMatcher
+}
+
+type rtMatchersKey struct {
+	rp      string
+	version string
+	method  OperationKind
+}
+
+// pathMatchersKey identifies the operation refs of the operation info, or of its action if act is not empty.
+type pathMatchersKey struct {
+	info *OperationInfo
+	act  string
+}
+
+type rtMatcher struct {
+	rt      string
+	info    *OperationInfo
+	matcher Matcher
+}
+
+type pathMatcher struct {
+	ppath   PathPatternStr
+	ref     jsonreference.Ref
+	matcher Matcher
+}
+
+// buildLookupMatchers builds the matchers of the whole index, which is then used by each lookup.
+// The index is not expected to be changed afterwards.
+func (idx *Index) buildLookupMatchers() {
+	m := &lookupMatchers{
+		rts:   map[rtMatchersKey][]rtMatcher{},
+		paths: map[pathMatchersKey][]pathMatcher{},
+	}
+	for rp, versions := range idx.ResourceProviders {
+		for version, methods := range versions {
+			for method, rts := range methods {
+				m.rts[rtMatchersKey{rp: rp, version: version, method: method}] = buildRTMatchers(rts)
+				for _, info := range rts {
+					m.paths[pathMatchersKey{info: info}] = buildPathMatchers(info.OperationRefs)
+					for act, oprefs := range info.Actions {
+						m.paths[pathMatchersKey{info: info, act: act}] = buildPathMatchers(oprefs)
+					}
+				}
+			}
+		}
+	}
+	idx.matchers = m
+}
+
+// rtMatchers returns the sorted RT matchers of the resource types of the RP, version and method.
+func (m *lookupMatchers) rtMatchers(rp, version string, method OperationKind, rts ResourceTypes) []rtMatcher {
+	if m != nil {
+		if l, ok := m.rts[rtMatchersKey{rp: rp, version: version, method: method}]; ok {
+			return l
+		}
+	}
+	return buildRTMatchers(rts)
+}
+
+// pathMatchers returns the sorted path matchers of the operation refs of the operation info, or of its action if act is not empty.
+func (m *lookupMatchers) pathMatchers(info *OperationInfo, act string, oprefs OperationRefs) []pathMatcher {
+	if m != nil {
+		if l, ok := m.paths[pathMatchersKey{info: info, act: act}]; ok {
+			return l
+		}
+	}
+	return buildPathMatchers(oprefs)
+}
+
+func buildRTMatchers(rts ResourceTypes) []rtMatcher {
+	var l []rtMatcher
+	for rt, info := range rts {
+		l = append(l, rtMatcher{
+			rt:      rt,
+			info:    info,
+			matcher: buildRTMatcher(rt),
+		})
+	}
+	// Sort the resource type matchers to match from the most specific to the most general
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].matcher.Less(l[j].matcher)
+	})
+	return l
+}
+
+func buildPathMatchers(oprefs OperationRefs) []pathMatcher {
+	var l []pathMatcher
+	for ppath, ref := range oprefs {
+		pathPattern := ParsePathPatternFromString(string(ppath))
+		m := Matcher{
+			PrefixSep: true,
+			Separater: "/",
+		}
+		for _, seg := range pathPattern.Segments {
+			m.Segments = append(m.Segments, MatchSegment{
+				Value:      seg.FixedName,
+				IsWildcard: seg.IsParameter,
+				IsAny:      seg.IsMulti,
+			})
+		}
+		l = append(l, pathMatcher{
+			ppath:   ppath,
+			ref:     ref,
+			matcher: m,
+		})
+	}
+	// Sort the path matchers to match from the most specific to the most general
+	sort.Slice(l, func(i, j int) bool {
+		return l[i].matcher.Less(l[j].matcher)
+	})
+	return l
+}
@@ -0,0 +1,132 @@
+package azidx
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// lroPollCollections are the (upper cased) collections of the LRO polling URLs, e.g. .../operationResults/{id}.
+var lroPollCollections = []string{"OPERATIONRESULTS", "OPERATIONSTATUSES", "ASYNCOPERATIONS"}
+
+// LROPoll is a polling URL of a long running operation, e.g. the URL in the Azure-AsyncOperation or Location response header.
+type LROPoll struct {
+	// Kind is the polling collection in the casing of the URL, e.g. operationResults, operationStatuses, asyncOperations.
+	Kind string `json:"kind"`
+	// RP is the RP that the polling URL belongs to, in the casing of the URL, e.g. Microsoft.Compute. It is empty if the URL has no RP.
+	RP string `json:"rp,omitempty"`
+	// Location is the location that the polling URL is scoped to, if any.
+	Location string `json:"location,omitempty"`
+	// ID is the id of the polled operation.
+	ID string `json:"id"`
+	// Origin is the long running operation that produced the polling URL, which is only set by the LROTracker.
+	Origin *LROOrigin `json:"origin,omitempty"`
+}
+
+// LROOrigin is the long running operation request that produced the polling URL.
+type LROOrigin struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	Ref         string `json:"ref"`
+	OperationID string `json:"operation_id,omitempty"`
+}
+
+// ParseLROPoll parses the URL as an LRO polling URL, i.e. the path ends with .../operationResults/{id}, .../operationStatuses/{id} or .../asyncOperations/{id}.
+// It returns nil if the URL is not an LRO polling URL.
+func ParseLROPoll(uRL url.URL) *LROPoll {
+	segs := strings.Split(strings.Trim(uRL.Path, "/"), "/")
+	if len(segs) < 2 {
+		return nil
+	}
+	kind := segs[len(segs)-2]
+	var ok bool
+	for _, c := range lroPollCollections {
+		if strings.EqualFold(c, kind) {
+			ok = true
+			break
+		}
+	}
+	if !ok || segs[len(segs)-1] == "" {
+		return nil
+	}
+	poll := &LROPoll{
+		Kind: kind,
+		ID:   segs[len(segs)-1],
+	}
+	for i := 0; i < len(segs)-3; i++ {
+		switch strings.ToUpper(segs[i]) {
+		case "PROVIDERS":
+			poll.RP = segs[i+1]
+		case "LOCATIONS":
+			poll.Location = segs[i+1]
+		}
+	}
+	return poll
+}
+
+// LROTracker links the LRO polling URLs back to the long running operations that produced them, while looking up a sequence of requests in order.
+// It is safe for concurrent use.
+type LROTracker struct {
+	mu sync.Mutex
+	// byURL maps the upper cased path of the polling URLs, from the response headers of the long running operations, to the operation.
+	byURL map[string]*LROOrigin
+	// byRP maps the upper cased RP to its most recent long running operation.
+	byRP map[string]*LROOrigin
+}
+
+func NewLROTracker() *LROTracker {
+	return &LROTracker{
+		byURL: map[string]*LROOrigin{},
+		byRP:  map[string]*LROOrigin{},
+	}
+}
+
+// Observe records the looked up request as the origin of the following polling URLs of its RP, if it is a long running operation.
+// For the index that has no operation metadata, any request other than GET is regarded as a potential long running operation.
+// The response header is optional, whose Azure-AsyncOperation and Location URLs are linked to this request exactly.
+func (t *LROTracker) Observe(method string, uRL url.URL, result *LookupResult, header http.Header) {
+	if result == nil || result.RP == Wildcard {
+		return
+	}
+	if meta := result.Metadata; meta != nil {
+		if !meta.LongRunning {
+			return
+		}
+	} else if strings.EqualFold(method, http.MethodGet) {
+		return
+	}
+	origin := &LROOrigin{
+		Method: strings.ToUpper(method),
+		URL:    uRL.String(),
+		Ref:    result.Ref.String(),
+	}
+	if result.Metadata != nil {
+		origin.OperationID = result.Metadata.OperationID
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.byRP[result.RP] = origin
+	for _, k := range []string{"Azure-AsyncOperation", "Location"} {
+		if v := header.Get(k); v != "" {
+			if u, err := url.Parse(v); err == nil {
+				t.byURL[strings.ToUpper(strings.TrimRight(u.Path, "/"))] = origin
+			}
+		}
+	}
+}
+
+// Link sets the origin of the LRO poll, which is the long running operation whose response header has the polling URL,
+// or otherwise the most recent long running operation of the same RP. The origin is left nil if there is no such operation.
+func (t *LROTracker) Link(poll *LROPoll, uRL url.URL) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if origin, ok := t.byURL[strings.ToUpper(strings.TrimRight(uRL.Path, "/"))]; ok {
+		poll.Origin = origin
+		return
+	}
+	if origin, ok := t.byRP[strings.ToUpper(poll.RP)]; ok {
+		poll.Origin = origin
+	}
+}
@@ -0,0 +1,259 @@
+package azidx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/magodo/azure-rest-api-index/azidx/record"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLROPoll(t *testing.T) {
+	cases := []struct {
+		url  string
+		poll *LROPoll
+	}{
+		{
+			url:  "/subscriptions/sub1/providers/Microsoft.Compute/locations/westus/operations/op1?api-version=ver1",
+			poll: nil,
+		},
+		{
+			url:  "/subscriptions/sub1/providers/Microsoft.Compute/locations/westus/operationResults/op1?api-version=ver1",
+			poll: &LROPoll{Kind: "operationResults", RP: "Microsoft.Compute", Location: "westus", ID: "op1"},
+		},
+		{
+			url:  "https://management.azure.com/subscriptions/sub1/resourceGroups/rg1/providers/Microsoft.Web/sites/site1/operationStatuses/op1/",
+			poll: &LROPoll{Kind: "operationStatuses", RP: "Microsoft.Web", ID: "op1"},
+		},
+		{
+			url:  "/subscriptions/sub1/providers/Microsoft.Network/locations/eastus/asyncOperations/op1",
+			poll: &LROPoll{Kind: "asyncOperations", RP: "Microsoft.Network", Location: "eastus", ID: "op1"},
+		},
+		{
+			url:  "/subscriptions/sub1/operationresults/op1",
+			poll: &LROPoll{Kind: "operationresults", ID: "op1"},
+		},
+		{
+			url:  "/operationResults",
+			poll: nil,
+		},
+	}
+	for _, tt := range cases {
+		uRL, err := url.Parse(tt.url)
+		require.NoError(t, err)
+		require.Equal(t, tt.poll, ParseLROPoll(*uRL), tt.url)
+	}
+}
+
+func TestLookupLROPoll(t *testing.T) {
+	index := &Index{
+		ResourceProviders: ResourceProviders{
+			"*": APIVersions{
+				"ver1": APIMethods{
+					"GET": ResourceTypes{
+						"/ASYNCOPERATIONS": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/PROVIDERS/{}/ASYNCOPERATIONS/{}": jsonreference.MustCreateRef("#*:VER1:GET:/ASYNCOPERATIONS::P1"),
+							},
+						},
+					},
+				},
+			},
+			"RP1": APIVersions{
+				"ver1": APIMethods{
+					"GET": ResourceTypes{
+						"/OPERATIONSTATUSES": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/PROVIDERS/RP1/OPERATIONSTATUSES/{}": jsonreference.MustCreateRef("#RP1:VER1:GET:/OPERATIONSTATUSES::P1"),
+							},
+						},
+					},
+					"PUT": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/PROVIDERS/RP1/FOOS/{}": jsonreference.MustCreateRef("#RP1:VER1:PUT:/FOOS::P1"),
+							},
+						},
+					},
+				},
+			},
+		},
+		Operations: map[string]*OperationMetadata{
+			"#RP1:VER1:PUT:/FOOS::P1": {
+				Path:        "/providers/RP1/foos/{fooName}",
+				OperationID: "Foos_CreateOrUpdate",
+				LongRunning: true,
+			},
+		},
+	}
+
+	lookup := func(method, rawURL string) (*LookupResult, error) {
+		uRL, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		return index.LookupOperation(method, *uRL)
+	}
+
+	// The polling URL matches as before, with the poll classified
+	result, err := lookup("GET", "/providers/rp1/operationStatuses/op1?api-version=ver1")
+	require.NoError(t, err)
+	require.Equal(t, "#RP1:VER1:GET:/OPERATIONSTATUSES::P1", result.Ref.String())
+	require.Equal(t, &LROPoll{Kind: "operationStatuses", RP: "rp1", ID: "op1"}, result.LROPoll)
+
+	result, err = lookup("GET", "/providers/rp2/asyncOperations/op1?api-version=ver1")
+	require.NoError(t, err)
+	require.Equal(t, Wildcard, result.RP)
+	require.Equal(t, &LROPoll{Kind: "asyncOperations", RP: "rp2", ID: "op1"}, result.LROPoll)
+
+	// The polling URL that matches nothing is still an error, with the poll classified
+	_, err = lookup("GET", "/providers/rp1/locations/westus/operationResults/op1?api-version=ver1")
+	require.ErrorIs(t, err, ErrLROPoll)
+	require.ErrorIs(t, err, ErrNoMatch)
+	require.EqualError(t, err, "lookup for /providers/rp1/locations/westus/operationResults/op1?api-version=ver1 (GET): matches nothing")
+	var lerr *LookupError
+	require.ErrorAs(t, err, &lerr)
+	require.Equal(t, LookupFailureRTUnmatched, lerr.Cause)
+	require.Equal(t, &LROPoll{Kind: "operationResults", RP: "rp1", Location: "westus", ID: "op1"}, lerr.LROPoll)
+
+	// The non-poll request is not classified
+	result, err = lookup("PUT", "/providers/rp1/foos/foo1?api-version=ver1")
+	require.NoError(t, err)
+	require.Nil(t, result.LROPoll)
+
+	// Only the GET request can be a poll
+	_, err = lookup("DELETE", "/providers/rp1/locations/westus/operationResults/op1?api-version=ver1")
+	require.ErrorIs(t, err, ErrNoMatch)
+	require.False(t, errors.Is(err, ErrLROPoll))
+
+	// The polls are excluded from the unmatched report and the coverage
+	report := NewUnmatchedReport(index, 0)
+	coverage := NewCoverage(index, CoverageOptions{})
+	for _, rawURL := range []string{
+		"/providers/rp1/locations/westus/operationResults/op1?api-version=ver1",
+		"/providers/rp1/bars/bar1?api-version=ver1",
+	} {
+		uRL, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		report.Record("GET", *uRL)
+		coverage.Record("GET", *uRL)
+	}
+	summary := report.Summary()
+	require.Equal(t, 1, summary.LROPolls)
+	require.Equal(t, 1, summary.Unmatched)
+	cov := coverage.Report()
+	require.Equal(t, 1, cov.LROPolls)
+	require.Equal(t, 1, cov.Unmatched)
+}
+
+func TestLROTracker(t *testing.T) {
+	index := &Index{
+		ResourceProviders: ResourceProviders{
+			"RP1": APIVersions{
+				"ver1": APIMethods{
+					"PUT": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/PROVIDERS/RP1/FOOS/{}": jsonreference.MustCreateRef("#RP1:VER1:PUT:/FOOS::P1"),
+							},
+						},
+					},
+				},
+			},
+		},
+		Operations: map[string]*OperationMetadata{
+			"#RP1:VER1:PUT:/FOOS::P1": {
+				Path:        "/providers/RP1/foos/{fooName}",
+				OperationID: "Foos_CreateOrUpdate",
+				LongRunning: true,
+			},
+		},
+	}
+	tracker := NewLROTracker()
+
+	observe := func(method, rawURL string, header http.Header) {
+		uRL, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		result, err := index.LookupOperation(method, *uRL)
+		require.NoError(t, err)
+		tracker.Observe(method, *uRL, result, header)
+	}
+	link := func(rawURL string) *LROOrigin {
+		uRL, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		poll := ParseLROPoll(*uRL)
+		require.NotNil(t, poll)
+		tracker.Link(poll, *uRL)
+		return poll.Origin
+	}
+
+	observe("PUT", "/providers/rp1/foos/foo1?api-version=ver1", http.Header{
+		"Azure-Asyncoperation": []string{"https://management.azure.com/providers/rp1/locations/westus/operationResults/op1?api-version=ver1"},
+	})
+	observe("PUT", "/providers/rp1/foos/foo2?api-version=ver1", nil)
+
+	// The polling URL in the response header is linked exactly
+	require.Equal(t, &LROOrigin{
+		Method:      "PUT",
+		URL:         "/providers/rp1/foos/foo1?api-version=ver1",
+		Ref:         "#RP1:VER1:PUT:/FOOS::P1",
+		OperationID: "Foos_CreateOrUpdate",
+	}, link("/providers/rp1/locations/westus/operationResults/op1?api-version=ver1"))
+
+	// Otherwise, it is linked to the most recent long running operation of the RP
+	require.Equal(t, "/providers/rp1/foos/foo2?api-version=ver1", link("/providers/rp1/locations/westus/operationResults/op2").URL)
+
+	// There is no long running operation of the RP
+	require.Nil(t, link("/providers/rp2/operationResults/op1"))
+}
+
+func TestLROTrackerFromLogs(t *testing.T) {
+	index, err := BuildIndexWithOptions(context.Background(), BuildOptions{SpecDir: "../testdata/spec", OperationMetadata: true})
+	require.NoError(t, err)
+
+	cases := []struct {
+		file string
+		read func(io.Reader) ([]record.Record, error)
+	}{
+		{"../testdata/log/terraform.log", record.ReadTerraformLog},
+		{"../testdata/log/azcli.log", record.ReadAzCLILog},
+	}
+	for _, tt := range cases {
+		f, err := os.Open(tt.file)
+		require.NoError(t, err)
+		records, err := tt.read(f)
+		f.Close()
+		require.NoError(t, err)
+
+		tracker := NewLROTracker()
+		var polls []*LROPoll
+		for _, rec := range records {
+			uRL, err := url.Parse(rec.URL)
+			require.NoError(t, err)
+			result, err := index.LookupOperation(rec.Method, *uRL)
+			var poll *LROPoll
+			if err != nil {
+				var lerr *LookupError
+				require.ErrorAs(t, err, &lerr)
+				poll = lerr.LROPoll
+			} else {
+				tracker.Observe(rec.Method, *uRL, result, rec.ResponseHeaders)
+				poll = result.LROPoll
+			}
+			if poll != nil {
+				tracker.Link(poll, *uRL)
+				polls = append(polls, poll)
+			}
+		}
+
+		// The poll of foo1 follows the LRO of foo2, but is linked to foo1 via the Azure-AsyncOperation header
+		require.Len(t, polls, 1, tt.file)
+		require.NotNil(t, polls[0].Origin, tt.file)
+		require.Equal(t, "https://management.azure.com/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15", polls[0].Origin.URL, tt.file)
+		require.Equal(t, "Foos_CreateOrUpdate", polls[0].Origin.OperationID, tt.file)
+	}
+}
@@ -0,0 +1,220 @@
+// Package mock provides the mock ARM server that answers each request by the x-ms-examples of the operation looked up by the index,
+// or by a minimal body generated from the response schema if there is no example.
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"github.com/magodo/azure-rest-api-index/azidx"
+)
+
+// StatusCodeHeader is the request header to choose the status code of the response. Otherwise, the first declared 2xx status code is chosen.
+const StatusCodeHeader = "X-Mock-Status-Code"
+
+// SourceHeader is the response header that tells where the response comes from: the example file, or "schema" for the generated body.
+const SourceHeader = "X-Mock-Source"
+
+// maxSchemaDepth limits the nesting of the generated body, e.g. for the recursive schemas.
+const maxSchemaDepth = 16
+
+// Server is the mock ARM server.
+type Server struct {
+	index    *azidx.Index
+	specdir  string
+	resolver *azidx.OperationResolver
+}
+
+var _ http.Handler = &Server{}
+
+// New creates the mock server by the index and the spec dir, where the commit of the spec dir has to be the same as the index.
+func New(index *azidx.Index, specdir string) *Server {
+	return &Server{
+		index:    index,
+		specdir:  specdir,
+		resolver: azidx.NewOperationResolver(specdir),
+	}
+}
+
+// ServeHTTP answers the request by the operation it matches.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	result, err := s.index.LookupOperation(req.Method, *req.URL)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "NoMatchingOperation", err.Error())
+		return
+	}
+	op, err := s.resolver.Resolve(result.Ref)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "ResolveOperationFailed", fmt.Sprintf("resolving %s: %v", result.Ref.String(), err))
+		return
+	}
+
+	code, err := statusCode(req, op)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "InvalidStatusCode", err.Error())
+		return
+	}
+
+	if path, resp, err := s.exampleResponse(op, code, *req.URL); err != nil {
+		writeError(w, http.StatusInternalServerError, "LoadExampleFailed", err.Error())
+		return
+	} else if resp != nil {
+		for k, v := range resp.Headers {
+			w.Header().Set(k, fmt.Sprint(v))
+		}
+		w.Header().Set(SourceHeader, path)
+		if len(resp.Body) != 0 {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.WriteHeader(code)
+		w.Write(resp.Body)
+		return
+	}
+
+	w.Header().Set(SourceHeader, "schema")
+	// The status code that is not declared falls back to the "default" response, as azidx.ValidateResponseBody does
+	resp, ok := op.Responses[strconv.Itoa(code)]
+	if !ok {
+		resp, ok = op.Responses["default"]
+	}
+	if !ok || resp.Schema == nil {
+		w.WriteHeader(code)
+		return
+	}
+	b, err := json.Marshal(minimalBody(op, resp.Schema, req.URL.Path, 0))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "GenerateBodyFailed", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(b)
+}
+
+// statusCode returns the status code chosen by the StatusCodeHeader, or the first declared 2xx status code (defaults to 200).
+func statusCode(req *http.Request, op *azidx.ResolvedOperation) (int, error) {
+	if v := req.Header.Get(StatusCodeHeader); v != "" {
+		code, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q: %v", StatusCodeHeader, v, err)
+		}
+		return code, nil
+	}
+	for _, code := range op.StatusCodes() {
+		if strings.HasPrefix(code, "2") {
+			return strconv.Atoi(code)
+		}
+	}
+	return http.StatusOK, nil
+}
+
+// exampleResponse returns the response of the status code from the example that best matches the request (see azidx.RankExamples) and has it, or nil if there is none.
+func (s *Server) exampleResponse(op *azidx.ResolvedOperation, code int, uRL url.URL) (string, *azidx.ExampleResponse, error) {
+	files, err := azidx.RankExamples(s.specdir, op, uRL)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, file := range files {
+		// The broken example is skipped, as is by the ranking
+		example, err := azidx.LoadExample(s.specdir, file.Path)
+		if err != nil {
+			continue
+		}
+		if resp, ok := example.Responses[strconv.Itoa(code)]; ok {
+			return file.Path, &resp, nil
+		}
+	}
+	return "", nil, nil
+}
+
+// minimalBody generates the minimal value of the schema, where only the required properties of an object are generated.
+// The "id" and "name" properties of the top level object (i.e. the resource) are filled by the request path, if modeled.
+// The circular $ref that are left unexpanded are resolved against the spec file of the operation.
+func minimalBody(op *azidx.ResolvedOperation, sch *spec.Schema, path string, depth int) interface{} {
+	if depth > maxSchemaDepth {
+		return nil
+	}
+	sch = op.ResolveSchema(sch)
+	if sch == nil {
+		return nil
+	}
+	props := map[string]spec.Schema{}
+	var required []string
+	collectProperties(op, sch, props, &required, 0)
+
+	typ := ""
+	if len(sch.Type) != 0 {
+		typ = sch.Type[0]
+	} else if len(props) != 0 {
+		typ = "object"
+	}
+	switch typ {
+	case "object":
+		obj := map[string]interface{}{}
+		for _, name := range required {
+			prop, ok := props[name]
+			if !ok {
+				continue
+			}
+			obj[name] = minimalBody(op, &prop, path, depth+1)
+		}
+		if depth == 0 {
+			if _, ok := props["id"]; ok {
+				obj["id"] = path
+			}
+			if _, ok := props["name"]; ok {
+				obj["name"] = path[strings.LastIndex(path, "/")+1:]
+			}
+		}
+		return obj
+	case "array":
+		return []interface{}{}
+	case "string":
+		if len(sch.Enum) != 0 {
+			return sch.Enum[0]
+		}
+		return ""
+	case "integer", "number":
+		return 0
+	case "boolean":
+		return false
+	}
+	return nil
+}
+
+// collectProperties collects the properties and the required properties of the schema, including the ones from the allOf.
+func collectProperties(op *azidx.ResolvedOperation, sch *spec.Schema, props map[string]spec.Schema, required *[]string, depth int) {
+	// Guard against the (unexpected) circular allOf
+	if depth > maxSchemaDepth {
+		return
+	}
+	sch = op.ResolveSchema(sch)
+	if sch == nil {
+		return
+	}
+	for i := range sch.AllOf {
+		collectProperties(op, &sch.AllOf[i], props, required, depth+1)
+	}
+	for name, prop := range sch.Properties {
+		props[name] = prop
+	}
+	*required = append(*required, sch.Required...)
+}
+
+// writeError writes the error in the ARM error response format.
+func writeError(w http.ResponseWriter, code int, errCode, message string) {
+	b, _ := json.Marshal(map[string]interface{}{
+		"error": map[string]string{
+			"code":    errCode,
+			"message": message,
+		},
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(b)
+}
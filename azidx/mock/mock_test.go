@@ -0,0 +1,124 @@
+package mock
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/magodo/azure-rest-api-index/azidx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	specdir := "../../testdata/spec"
+	index, err := azidx.BuildIndex(specdir, "", nil)
+	require.NoError(t, err)
+	server := New(index, specdir)
+
+	do := func(method, url string, code string) (*http.Response, map[string]interface{}) {
+		req := httptest.NewRequest(method, url, nil)
+		if code != "" {
+			req.Header.Set(StatusCodeHeader, code)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		resp := w.Result()
+		var body map[string]interface{}
+		if w.Body.Len() != 0 {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		}
+		return resp, body
+	}
+
+	// From the example
+	resp, body := do("GET", "/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/examples/Foos_Get.json", resp.Header.Get(SourceHeader))
+	require.Equal(t, "Succeeded", body["properties"].(map[string]interface{})["provisioningState"])
+
+	// From the example that best matches the request
+	resp, body = do("GET", "/providers/Microsoft.Dummy/foos/foo2?api-version=2023-05-15", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/examples/Foos_GetDisabled.json", resp.Header.Get(SourceHeader))
+	require.Equal(t, false, body["properties"].(map[string]interface{})["enabled"])
+
+	// The chosen status code, with the headers of the example
+	resp, body = do("PUT", "/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15", "201")
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Contains(t, resp.Header.Get("Azure-AsyncOperation"), "/operationStatuses/op1")
+	require.Equal(t, "Creating", body["properties"].(map[string]interface{})["provisioningState"])
+
+	// Generated by the schema, as the list operation has no example
+	resp, body = do("GET", "/providers/Microsoft.Dummy/foos?api-version=2023-05-15", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "schema", resp.Header.Get(SourceHeader))
+	require.Equal(t, map[string]interface{}{}, body)
+
+	// Generated by the schema of the "default" response, for the status code that is not declared
+	resp, body = do("GET", "/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15", "500")
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	require.Equal(t, "schema", resp.Header.Get(SourceHeader))
+	require.Equal(t, map[string]interface{}{}, body)
+
+	// Unmatched
+	resp, body = do("GET", "/providers/Microsoft.Dummy/bazs/baz1?api-version=2023-05-15", "")
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, "NoMatchingOperation", body["error"].(map[string]interface{})["code"])
+}
+
+func TestMinimalBody(t *testing.T) {
+	ref := jsonreference.MustCreateRef("dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1{fooName}/put")
+	op, err := azidx.ResolveOperation("../../testdata/spec", ref)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"id":       "/providers/Microsoft.Dummy/foos/foo1",
+		"name":     "foo1",
+		"location": "",
+	}, minimalBody(op, op.Responses["200"].Schema, "/providers/Microsoft.Dummy/foos/foo1", 0))
+	require.Equal(t, map[string]interface{}{}, minimalBody(op, op.Responses["default"].Schema, "/providers/Microsoft.Dummy/foos/foo1", 0))
+}
+
+func TestMinimalBodyCircularRef(t *testing.T) {
+	specdir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(specdir, "node.json"), []byte(`{
+  "swagger": "2.0",
+  "info": {"title": "Node", "version": "2023-05-15"},
+  "paths": {
+    "/providers/Microsoft.Dummy/nodes/{nodeName}": {
+      "get": {
+        "operationId": "Nodes_Get",
+        "responses": {
+          "200": {"description": "OK", "schema": {"$ref": "#/definitions/Node"}}
+        }
+      }
+    }
+  },
+  "definitions": {
+    "Node": {
+      "type": "object",
+      "properties": {
+        "label": {"type": "string"},
+        "next": {"$ref": "#/definitions/Node"}
+      },
+      "required": ["label", "next"]
+    }
+  }
+}`), 0644))
+	ref := jsonreference.MustCreateRef("node.json#/paths/~1providers~1Microsoft.Dummy~1nodes~1{nodeName}/get")
+	op, err := azidx.ResolveOperation(specdir, ref)
+	require.NoError(t, err)
+
+	// The circular $ref is resolved until the max schema depth
+	body := minimalBody(op, op.Responses["200"].Schema, "/providers/Microsoft.Dummy/nodes/node1", 0)
+	var depth int
+	for node, ok := body.(map[string]interface{}); ok; node, ok = node["next"].(map[string]interface{}) {
+		require.Contains(t, node, "label")
+		depth++
+	}
+	require.Equal(t, maxSchemaDepth+1, depth)
+	require.Equal(t, "", body.(map[string]interface{})["next"].(map[string]interface{})["label"])
+}
@@ -0,0 +1,246 @@
+// Package proxy provides the reverse proxy that forwards the ARM requests to the upstream, and records each request annotated with its Swagger operation.
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/magodo/azure-rest-api-index/azidx"
+)
+
+// DefaultUpstream is the ARM endpoint of the public cloud.
+const DefaultUpstream = "https://management.azure.com"
+
+// Record is the record of a proxied request, which is written as a line of JSON.
+type Record struct {
+	Method string `json:"method"`
+	// URL is the upstream URL of the request.
+	URL string `json:"url"`
+	// StatusCode is the status code of the upstream response, which is 0 if the upstream can't be reached.
+	StatusCode int    `json:"status_code,omitempty"`
+	Ref        string `json:"ref,omitempty"`
+	// OperationID is the operationId of the matched operation, if the index has the operation metadata.
+	OperationID string `json:"operation_id,omitempty"`
+	// Error is the error of the lookup, or of reaching the upstream.
+	Error string `json:"error,omitempty"`
+	// Cause is the likely cause of the request matching nothing.
+	Cause string `json:"cause,omitempty"`
+	// LROPoll is set if the request is polling a long running operation, linked to the operation that produced it if possible.
+	LROPoll *azidx.LROPoll `json:"lro_poll,omitempty"`
+	// RequestWarnings and ResponseWarnings are the violations of the request and response bodies against the matched operation.
+	// They are only available if the spec dir is specified.
+	RequestWarnings  []azidx.ValidationError `json:"request_warnings,omitempty"`
+	ResponseWarnings []azidx.ValidationError `json:"response_warnings,omitempty"`
+	// ValidationError is the error of validating the bodies, e.g. the body is not JSON.
+	ValidationError string `json:"validation_error,omitempty"`
+}
+
+// Options are the options of the proxy.
+type Options struct {
+	// Upstream is the URL that the requests are forwarded to. Defaults to DefaultUpstream.
+	Upstream string
+	// SpecDir is the optional spec dir, of the same commit as the index, which enables validating the request and response bodies.
+	SpecDir string
+	// Transport is used to send the requests to the upstream. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+// Proxy is the reverse proxy that records each request to the output.
+type Proxy struct {
+	index     *azidx.Index
+	validator *azidx.Validator
+	lro       *azidx.LROTracker
+	rp        *httputil.ReverseProxy
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+var _ http.Handler = &Proxy{}
+
+type requestBodyKey struct{}
+
+// inboundURLKey is the context key of the URL of the inbound request, which is looked up instead of the upstream URL,
+// as the path prefix of the upstream changes the request path.
+type inboundURLKey struct{}
+
+// New creates the proxy, which writes each record as a line of JSON to the output.
+func New(index *azidx.Index, out io.Writer, opts Options) (*Proxy, error) {
+	upstream := opts.Upstream
+	if upstream == "" {
+		upstream = DefaultUpstream
+	}
+	target, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("parsing upstream %s: %v", upstream, err)
+	}
+	if target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("upstream %s is not an absolute URL", upstream)
+	}
+	p := &Proxy{
+		index: index,
+		lro:   azidx.NewLROTracker(),
+		out:   out,
+	}
+	if opts.SpecDir != "" {
+		p.validator = azidx.NewValidator(index, opts.SpecDir)
+	}
+	p.rp = &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.Out = r.Out.WithContext(context.WithValue(r.Out.Context(), inboundURLKey{}, r.In.URL))
+			r.SetURL(target)
+			r.Out.Host = target.Host
+		},
+		Transport:      opts.Transport,
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler:   p.errorHandler,
+	}
+	return p, nil
+}
+
+// ServeHTTP forwards the request to the upstream, and records it.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("reading request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	req = req.WithContext(context.WithValue(req.Context(), requestBodyKey{}, body))
+	p.rp.ServeHTTP(w, req)
+}
+
+// modifyResponse records the request with the upstream response, whose body is read and then restored.
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("reading response body: %v", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	rec := p.lookup(resp.Request, resp.Header)
+	rec.StatusCode = resp.StatusCode
+	if p.validator != nil && rec.Ref != "" {
+		if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+			if body, err = gunzip(body); err != nil {
+				rec.ValidationError = fmt.Sprintf("decompressing response body: %v", err)
+			}
+		}
+		if rec.ValidationError == "" {
+			p.validate(&rec, resp.Request, resp.StatusCode, body)
+		}
+	}
+	p.write(rec)
+	return nil
+}
+
+// errorHandler records the request that fails to reach the upstream.
+func (p *Proxy) errorHandler(w http.ResponseWriter, req *http.Request, err error) {
+	rec := p.lookup(req, nil)
+	if rec.Error == "" {
+		rec.Error = err.Error()
+	}
+	p.write(rec)
+	w.WriteHeader(http.StatusBadGateway)
+}
+
+// lookup builds the record of the outgoing request, with the lookup result.
+// The response header, if any, links the polling URLs of the long running operation to the request.
+func (p *Proxy) lookup(req *http.Request, header http.Header) Record {
+	rec := Record{
+		Method: req.Method,
+		URL:    req.URL.String(),
+	}
+	uRL := inboundURL(req)
+	result, err := p.index.LookupOperation(req.Method, uRL)
+	if err != nil {
+		var lerr *azidx.LookupError
+		if errors.As(err, &lerr) && lerr.LROPoll != nil {
+			p.lro.Link(lerr.LROPoll, uRL)
+			rec.LROPoll = lerr.LROPoll
+			return rec
+		}
+		rec.Error = err.Error()
+		if lerr != nil {
+			rec.Cause = string(lerr.Cause)
+		}
+		return rec
+	}
+	p.lro.Observe(req.Method, uRL, result, header)
+	if result.LROPoll != nil {
+		p.lro.Link(result.LROPoll, uRL)
+		rec.LROPoll = result.LROPoll
+	}
+	rec.Ref = result.Ref.String()
+	if result.Metadata != nil {
+		rec.OperationID = result.Metadata.OperationID
+	}
+	return rec
+}
+
+// validate validates the request and response bodies of the matched request.
+func (p *Proxy) validate(rec *Record, req *http.Request, statusCode int, respBody []byte) {
+	reqBody, _ := req.Context().Value(requestBodyKey{}).([]byte)
+	uRL := inboundURL(req)
+	result, err := p.validator.ValidateRequest(req.Method, uRL, reqBody)
+	if err != nil {
+		rec.ValidationError = fmt.Sprintf("validating request: %v", err)
+		return
+	}
+	rec.RequestWarnings = result.Errors
+	if rec.OperationID == "" {
+		rec.OperationID = result.OperationID
+	}
+	result, err = p.validator.ValidateResponse(req.Method, uRL, statusCode, respBody)
+	if err != nil {
+		rec.ValidationError = fmt.Sprintf("validating response: %v", err)
+		return
+	}
+	rec.ResponseWarnings = result.Errors
+}
+
+// inboundURL returns the URL of the inbound request of the outgoing request.
+func inboundURL(req *http.Request) url.URL {
+	if u, ok := req.Context().Value(inboundURLKey{}).(*url.URL); ok {
+		return *u
+	}
+	return *req.URL
+}
+
+func (p *Proxy) write(rec Record) {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		azidx.GetLogger().Error("marshal record", "error", err)
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.out.Write(append(b, '\n')); err != nil {
+		azidx.GetLogger().Error("writing record", "error", err)
+	}
+}
+
+func gunzip(b []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
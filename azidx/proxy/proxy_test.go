@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/magodo/azure-rest-api-index/azidx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxy(t *testing.T) {
+	specdir := "../../testdata/spec"
+	index, err := azidx.BuildIndex(specdir, "", nil)
+	require.NoError(t, err)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			w.WriteHeader(http.StatusCreated)
+			io.Copy(w, r.Body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer upstream.Close()
+
+	var out bytes.Buffer
+	p, err := New(index, &out, Options{Upstream: upstream.URL, SpecDir: specdir})
+	require.NoError(t, err)
+	server := httptest.NewServer(p)
+	defer server.Close()
+
+	body := `{"location": "westus", "properties": {"size": "1", "provisioningState": "Succeeded"}}`
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15", strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	b, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.Equal(t, body, string(b))
+
+	resp, err = http.Get(server.URL + "/providers/Microsoft.Dummy/bazs/baz1?api-version=2023-05-15")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	var records []Record
+	dec := json.NewDecoder(&out)
+	for dec.More() {
+		var rec Record
+		require.NoError(t, dec.Decode(&rec))
+		records = append(records, rec)
+	}
+	require.Len(t, records, 2)
+
+	rec := records[0]
+	require.Equal(t, http.MethodPut, rec.Method)
+	require.Equal(t, upstream.URL+"/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15", rec.URL)
+	require.Equal(t, http.StatusCreated, rec.StatusCode)
+	require.Contains(t, rec.Ref, "2023-05-15/foo.json")
+	require.Equal(t, "Foos_CreateOrUpdate", rec.OperationID)
+	require.Empty(t, rec.Error)
+	require.Empty(t, rec.ValidationError)
+	require.Equal(t, []azidx.ValidationError{
+		{Pointer: "/properties/provisioningState", Kind: azidx.ValidationErrorReadOnly, Message: rec.RequestWarnings[0].Message},
+		{Pointer: "/properties/size", Kind: azidx.ValidationErrorTypeMismatch, Message: rec.RequestWarnings[1].Message},
+	}, rec.RequestWarnings)
+	require.Len(t, rec.ResponseWarnings, 1)
+	require.Equal(t, azidx.ValidationErrorTypeMismatch, rec.ResponseWarnings[0].Kind)
+
+	rec = records[1]
+	require.Equal(t, http.StatusNotFound, rec.StatusCode)
+	require.Empty(t, rec.Ref)
+	require.Contains(t, rec.Error, "matches nothing")
+	require.Equal(t, string(azidx.LookupFailureRTUnmatched), rec.Cause)
+
+	// The inbound request path is looked up, regardless of the path prefix of the upstream
+	out.Reset()
+	p, err = New(index, &out, Options{Upstream: upstream.URL + "/prefix"})
+	require.NoError(t, err)
+	prefixed := httptest.NewServer(p)
+	defer prefixed.Close()
+	req, err = http.NewRequest(http.MethodPut, prefixed.URL+"/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15", strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	rec = Record{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &rec))
+	require.Equal(t, upstream.URL+"/prefix/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15", rec.URL)
+	require.Contains(t, rec.Ref, "2023-05-15/foo.json")
+	require.Empty(t, rec.Error)
+
+	_, err = New(index, &out, Options{Upstream: "/foo"})
+	require.ErrorContains(t, err, "is not an absolute URL")
+}
@@ -0,0 +1,101 @@
+package record
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// azCLIPolicyLogRegexp matches the request/response logs of the Azure CLI (with --debug), e.g.
+	// "DEBUG: cli.azure.cli.core.sdk.policies: Request URL: 'https://management.azure.com/...'"
+	azCLIPolicyLogRegexp = regexp.MustCompile(`^DEBUG: cli\.azure\.cli\.core\.sdk\.policies: (.*)$`)
+	// azCLIHeaderRegexp matches a header line, e.g. "    'Content-Type': 'application/json'"
+	azCLIHeaderRegexp = regexp.MustCompile(`^\s+'([^']+)': '(.*)'$`)
+)
+
+const azCLINoBody = "This request has no body"
+
+// ReadAzCLILog reads records from the debug output of the Azure CLI (i.e. "az --debug").
+func ReadAzCLILog(r io.Reader) ([]Record, error) {
+	var records []Record
+	var (
+		rec *Record
+		// body is the body lines being collected, which is either the request body or the response content
+		body              *[]string
+		reqBody, respBody []string
+		// inRespHeaders indicates the response headers are being collected
+		inRespHeaders bool
+	)
+	flush := func() {
+		if rec == nil {
+			return
+		}
+		rec.RequestBody = jsonBody([]byte(strings.Join(reqBody, "\n")))
+		rec.ResponseBody = jsonBody([]byte(strings.Join(respBody, "\n")))
+		records = append(records, *rec)
+		rec, body, reqBody, respBody, inRespHeaders = nil, nil, nil, nil, false
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		matches := azCLIPolicyLogRegexp.FindStringSubmatch(scanner.Text())
+		if matches == nil {
+			// Logs from other loggers end the body and the headers
+			body, inRespHeaders = nil, false
+			continue
+		}
+		msg := matches[1]
+		if inRespHeaders && rec != nil {
+			if m := azCLIHeaderRegexp.FindStringSubmatch(msg); m != nil {
+				if rec.ResponseHeaders == nil {
+					rec.ResponseHeaders = http.Header{}
+				}
+				rec.ResponseHeaders.Add(m[1], m[2])
+				continue
+			}
+			inRespHeaders = false
+		}
+		switch {
+		case strings.HasPrefix(msg, "Request URL: "):
+			flush()
+			rec = &Record{URL: strings.Trim(strings.TrimPrefix(msg, "Request URL: "), "'")}
+		case rec == nil:
+			continue
+		case strings.HasPrefix(msg, "Request method: "):
+			rec.Method = strings.Trim(strings.TrimPrefix(msg, "Request method: "), "'")
+			body = nil
+		case msg == "Request headers:":
+			body = nil
+		case msg == "Response headers:":
+			body, inRespHeaders = nil, true
+		case msg == "Request body:":
+			body = &reqBody
+		case strings.HasPrefix(msg, "Response status: "):
+			code, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(msg, "Response status: ")))
+			if err != nil {
+				return nil, fmt.Errorf("malformed response status %q: %v", msg, err)
+			}
+			rec.StatusCode = code
+			body = nil
+		case msg == "Response content:":
+			body = &respBody
+		case msg == azCLINoBody:
+			body = nil
+		default:
+			if body != nil {
+				*body = append(*body, msg)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan error: %v", err)
+	}
+	flush()
+	return records, nil
+}
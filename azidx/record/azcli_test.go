@@ -0,0 +1,59 @@
+package record
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadAzCLILog(t *testing.T) {
+	f, err := os.Open("../../testdata/log/azcli.log")
+	require.NoError(t, err)
+	defer f.Close()
+	records, err := ReadAzCLILog(f)
+	require.NoError(t, err)
+	require.Equal(t, []Record{
+		{
+			Method:       "PUT",
+			URL:          "https://management.azure.com/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15",
+			RequestBody:  json.RawMessage(`{"location": "westus"}`),
+			StatusCode:   201,
+			ResponseBody: json.RawMessage(`{"id":"/providers/Microsoft.Dummy/foos/foo1","location":"westus"}`),
+			ResponseHeaders: http.Header{
+				"Cache-Control":        []string{"no-cache"},
+				"Azure-Asyncoperation": []string{"https://management.azure.com/providers/Microsoft.Dummy/locations/westus/operationStatuses/op1?api-version=2023-05-15"},
+				"Content-Type":         []string{"application/json; charset=utf-8"},
+			},
+		},
+		{
+			Method:      "PUT",
+			URL:         "https://management.azure.com/providers/Microsoft.Dummy/foos/foo2?api-version=2023-05-15",
+			RequestBody: json.RawMessage(`{"location": "westus"}`),
+			StatusCode:  202,
+			ResponseHeaders: http.Header{
+				"Location": []string{"https://management.azure.com/providers/Microsoft.Dummy/locations/westus/operationResults/op2?api-version=2023-05-15"},
+			},
+		},
+		{
+			Method:       "GET",
+			URL:          "https://management.azure.com/providers/Microsoft.Dummy/locations/westus/operationStatuses/op1?api-version=2023-05-15",
+			StatusCode:   200,
+			ResponseBody: json.RawMessage(`{"status":"Succeeded"}`),
+			ResponseHeaders: http.Header{
+				"Content-Type": []string{"application/json; charset=utf-8"},
+			},
+		},
+		{
+			Method:       "GET",
+			URL:          "https://management.azure.com/providers/Microsoft.Dummy/foos?api-version=2023-05-15",
+			StatusCode:   200,
+			ResponseBody: json.RawMessage(`{"value": []}`),
+			ResponseHeaders: http.Header{
+				"Content-Type": []string{"application/json; charset=utf-8"},
+			},
+		},
+	}, records)
+}
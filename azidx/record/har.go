@@ -0,0 +1,150 @@
+package record
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// DefaultARMHosts are the ARM endpoints of the public and sovereign clouds.
+var DefaultARMHosts = []string{
+	"management.azure.com",
+	"management.chinacloudapi.cn",
+	"management.usgovcloudapi.net",
+}
+
+// HARAnnotationKey is the custom field (as is required by HAR to start with an underscore) added to each annotated entry.
+const HARAnnotationKey = "_azureRestApiIndex"
+
+// HAR represents a HTTP Archive 1.2, only with the fields that are needed to build the records.
+// See: http://www.softwareishard.com/blog/har-12-spec/
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+type HARLog struct {
+	Version string     `json:"version"`
+	Entries []HAREntry `json:"entries"`
+}
+
+type HAREntry struct {
+	Request  HARRequest  `json:"request"`
+	Response HARResponse `json:"response"`
+}
+
+type HARRequest struct {
+	Method   string       `json:"method"`
+	URL      string       `json:"url"`
+	PostData *HARPostData `json:"postData,omitempty"`
+}
+
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type HARResponse struct {
+	Status  int         `json:"status"`
+	Headers []HARHeader `json:"headers,omitempty"`
+	Content HARContent  `json:"content"`
+}
+
+type HARHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type HARContent struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// ReadHAR reads the HAR content and returns one record for each entry, in order.
+// The bodies that are not JSON are dropped.
+func ReadHAR(b []byte) ([]Record, error) {
+	var har HAR
+	if err := json.Unmarshal(b, &har); err != nil {
+		return nil, fmt.Errorf("unmarshal HAR: %v", err)
+	}
+	var records []Record
+	for i, entry := range har.Log.Entries {
+		rec := Record{
+			Method:     entry.Request.Method,
+			URL:        entry.Request.URL,
+			StatusCode: entry.Response.Status,
+		}
+		if pd := entry.Request.PostData; pd != nil {
+			rec.RequestBody = jsonBody([]byte(pd.Text))
+		}
+		for _, h := range entry.Response.Headers {
+			if rec.ResponseHeaders == nil {
+				rec.ResponseHeaders = http.Header{}
+			}
+			rec.ResponseHeaders.Add(h.Name, h.Value)
+		}
+		content := entry.Response.Content
+		if content.Text != "" {
+			body := []byte(content.Text)
+			if content.Encoding == "base64" {
+				var err error
+				body, err = base64.StdEncoding.DecodeString(content.Text)
+				if err != nil {
+					return nil, fmt.Errorf("decoding the response content of entry %d: %v", i, err)
+				}
+			}
+			rec.ResponseBody = jsonBody(body)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// AnnotateHAR adds the annotations to the entries of the HAR content, which are keyed by the entry index. The other content is kept as is.
+func AnnotateHAR(b []byte, annotations map[int]interface{}) ([]byte, error) {
+	var har map[string]interface{}
+	if err := json.Unmarshal(b, &har); err != nil {
+		return nil, fmt.Errorf("unmarshal HAR: %v", err)
+	}
+	log, ok := har["log"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf(`HAR has no "log"`)
+	}
+	entries, ok := log["entries"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf(`HAR has no "log.entries"`)
+	}
+	for i, annotation := range annotations {
+		if i < 0 || i >= len(entries) {
+			return nil, fmt.Errorf("entry index %d out of range", i)
+		}
+		entry, ok := entries[i].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("entry %d is not an object", i)
+		}
+		entry[HARAnnotationKey] = annotation
+	}
+	return json.MarshalIndent(har, "", "  ")
+}
+
+// HasHost tells whether the host of the record's URL is one of the hosts (case insensitively).
+func HasHost(rec Record, hosts []string) bool {
+	uRL, err := url.Parse(rec.URL)
+	if err != nil {
+		return false
+	}
+	return slices.ContainsFunc(hosts, func(host string) bool {
+		return strings.EqualFold(host, uRL.Hostname())
+	})
+}
+
+func jsonBody(b []byte) json.RawMessage {
+	if !json.Valid(b) {
+		return nil
+	}
+	return b
+}
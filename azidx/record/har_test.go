@@ -0,0 +1,74 @@
+package record
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadHAR(t *testing.T) {
+	b, err := os.ReadFile("../../testdata/har/sample.har")
+	require.NoError(t, err)
+	records, err := ReadHAR(b)
+	require.NoError(t, err)
+	require.Equal(t, []Record{
+		{
+			Method:       "PUT",
+			URL:          "https://management.azure.com/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15",
+			RequestBody:  json.RawMessage(`{"location": "westus"}`),
+			StatusCode:   201,
+			ResponseBody: json.RawMessage(`{"id": "/providers/Microsoft.Dummy/foos/foo1", "location": "westus"}`),
+			ResponseHeaders: http.Header{
+				"Azure-Asyncoperation": []string{"https://management.azure.com/providers/Microsoft.Dummy/locations/westus/operationStatuses/op1?api-version=2023-05-15"},
+			},
+		},
+		{
+			Method:     "GET",
+			URL:        "https://portal.azure.com/favicon.ico",
+			StatusCode: 200,
+		},
+		{
+			Method:       "GET",
+			URL:          "https://management.azure.com/providers/Microsoft.Dummy/foos?api-version=2023-05-15",
+			StatusCode:   200,
+			ResponseBody: json.RawMessage(`{"value": []}`),
+		},
+	}, records)
+
+	var armRecords []Record
+	for _, rec := range records {
+		if HasHost(rec, DefaultARMHosts) {
+			armRecords = append(armRecords, rec)
+		}
+	}
+	require.Len(t, armRecords, 2)
+}
+
+func TestAnnotateHAR(t *testing.T) {
+	b, err := os.ReadFile("../../testdata/har/sample.har")
+	require.NoError(t, err)
+	out, err := AnnotateHAR(b, map[int]interface{}{
+		0: map[string]string{"ref": "foo.json#/paths/~1foos/put"},
+	})
+	require.NoError(t, err)
+
+	var har map[string]interface{}
+	require.NoError(t, json.Unmarshal(out, &har))
+	entries := har["log"].(map[string]interface{})["entries"].([]interface{})
+	require.Len(t, entries, 3)
+	require.Equal(t, map[string]interface{}{"ref": "foo.json#/paths/~1foos/put"}, entries[0].(map[string]interface{})[HARAnnotationKey])
+	require.NotContains(t, entries[1].(map[string]interface{}), HARAnnotationKey)
+	// Other fields are kept
+	require.Equal(t, "WebInspector", har["log"].(map[string]interface{})["creator"].(map[string]interface{})["name"])
+
+	// The annotated HAR can still be read
+	records, err := ReadHAR(out)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	_, err = AnnotateHAR(b, map[int]interface{}{3: nil})
+	require.Error(t, err)
+}
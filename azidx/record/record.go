@@ -0,0 +1,50 @@
+package record
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Record represents a recorded ARM request, optionally together with its response.
+type Record struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	// RequestBody is the JSON request body, if any.
+	RequestBody json.RawMessage `json:"request_body,omitempty"`
+	// StatusCode is the response status code, which is 0 if the response is not recorded.
+	StatusCode int `json:"status_code,omitempty"`
+	// ResponseBody is the JSON response body, if any.
+	ResponseBody json.RawMessage `json:"response_body,omitempty"`
+	// ResponseHeaders are the response headers, if any, e.g. the Azure-AsyncOperation header of a long running operation.
+	ResponseHeaders http.Header `json:"response_headers,omitempty"`
+}
+
+// ReadJSONL reads records from the JSON Lines input, where each line is a JSON encoded Record.
+// Empty lines are ignored.
+func ReadJSONL(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	// Request/response bodies can be large
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	var lineno int
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			return nil, fmt.Errorf("unmarshal line %d: %v", lineno, err)
+		}
+		records = append(records, rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan error: %v", err)
+	}
+	return records, nil
+}
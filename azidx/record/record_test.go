@@ -0,0 +1,35 @@
+package record
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadJSONL(t *testing.T) {
+	input := `
+{"method": "GET", "url": "https://management.azure.com/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15", "status_code": 200, "response_body": {"location": "westus"}}
+
+{"method": "PUT", "url": "https://management.azure.com/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15", "request_body": {"location": "westus"}}
+`
+	records, err := ReadJSONL(strings.NewReader(input))
+	require.NoError(t, err)
+	require.Equal(t, []Record{
+		{
+			Method:       "GET",
+			URL:          "https://management.azure.com/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15",
+			StatusCode:   200,
+			ResponseBody: json.RawMessage(`{"location": "westus"}`),
+		},
+		{
+			Method:      "PUT",
+			URL:         "https://management.azure.com/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15",
+			RequestBody: json.RawMessage(`{"location": "westus"}`),
+		},
+	}, records)
+
+	_, err = ReadJSONL(strings.NewReader(`{"method": "GET"`))
+	require.Error(t, err)
+}
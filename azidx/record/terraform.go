@@ -0,0 +1,140 @@
+package record
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// tfLogEntryRegexp matches the first line of each log entry, e.g. "2023-06-01T10:00:00.123+0800 [DEBUG] provider.terraform-provider-azurerm_v3.59.0_x5: ..."
+	tfLogEntryRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\S+ \[(TRACE|DEBUG|INFO|WARN|ERROR)\] `)
+	// tfLogSuffixRegexp matches the key-value pairs appended to the provider's log entry by Terraform.
+	tfLogSuffixRegexp = regexp.MustCompile(`:\s+(@\S+=\S+\s+)*timestamp=\S+\s*$`)
+	// tfResponseRegexp matches the leading message of a response dump, which contains the request URL.
+	tfResponseRegexp = regexp.MustCompile(`^(.+): $`)
+)
+
+const (
+	tfRequestMark  = "AzureRM Request: "
+	tfResponseMark = "AzureRM Response for "
+)
+
+// ReadTerraformLog reads records from the Terraform log of the azurerm provider, with TF_LOG=DEBUG set.
+// The request and response dumps are logged by the provider as "AzureRM Request: ..." and "AzureRM Response for <url>: ...".
+// Each response is paired with the earliest request of the same URL that has no response yet.
+func ReadTerraformLog(r io.Reader) ([]Record, error) {
+	var entries []string
+	var entry []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if tfLogEntryRegexp.MatchString(line) && len(entry) != 0 {
+			entries = append(entries, strings.Join(entry, "\n"))
+			entry = nil
+		}
+		entry = append(entry, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scan error: %v", err)
+	}
+	if len(entry) != 0 {
+		entries = append(entries, strings.Join(entry, "\n"))
+	}
+
+	var records []*Record
+	// pending records the indexes of the records that are waiting for the response, keyed by the URL
+	pending := map[string][]int{}
+	for _, entry := range entries {
+		entry = tfLogSuffixRegexp.ReplaceAllString(entry, "")
+		if idx := strings.Index(entry, tfRequestMark); idx != -1 {
+			rec, err := parseTerraformRequestDump(entry[idx+len(tfRequestMark):])
+			if err != nil {
+				return nil, err
+			}
+			pending[rec.URL] = append(pending[rec.URL], len(records))
+			records = append(records, rec)
+			continue
+		}
+		if idx := strings.Index(entry, tfResponseMark); idx != -1 {
+			msg, dump, ok := strings.Cut(entry[idx+len(tfResponseMark):], "\n")
+			if !ok {
+				continue
+			}
+			matches := tfResponseRegexp.FindStringSubmatch(msg)
+			if matches == nil {
+				continue
+			}
+			uRL := matches[1]
+			l := pending[uRL]
+			if len(l) == 0 {
+				continue
+			}
+			pending[uRL] = l[1:]
+			if err := parseTerraformResponseDump(records[l[0]], dump); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var out []Record
+	for _, rec := range records {
+		out = append(out, *rec)
+	}
+	return out, nil
+}
+
+// splitHTTPDump splits the HTTP dump into the start line, the headers and the body.
+func splitHTTPDump(dump string) (string, http.Header, string) {
+	dump = strings.ReplaceAll(dump, "\r\n", "\n")
+	head, body, _ := strings.Cut(dump, "\n\n")
+	lines := strings.Split(head, "\n")
+	headers := http.Header{}
+	for _, line := range lines[1:] {
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		headers.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+	}
+	return strings.TrimSpace(lines[0]), headers, strings.TrimSpace(body)
+}
+
+func parseTerraformRequestDump(dump string) (*Record, error) {
+	// The dump starts with a new line
+	dump = strings.TrimLeft(dump, "\r\n")
+	startLine, headers, body := splitHTTPDump(dump)
+	fields := strings.Fields(startLine)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("malformed request line %q", startLine)
+	}
+	rec := &Record{
+		Method:      fields[0],
+		URL:         "https://" + headers.Get("Host") + fields[1],
+		RequestBody: jsonBody([]byte(body)),
+	}
+	return rec, nil
+}
+
+func parseTerraformResponseDump(rec *Record, dump string) error {
+	startLine, headers, body := splitHTTPDump(dump)
+	fields := strings.Fields(startLine)
+	if len(fields) < 2 {
+		return fmt.Errorf("malformed status line %q", startLine)
+	}
+	code, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return fmt.Errorf("malformed status line %q: %v", startLine, err)
+	}
+	rec.StatusCode = code
+	if len(headers) != 0 {
+		rec.ResponseHeaders = headers
+	}
+	rec.ResponseBody = jsonBody([]byte(body))
+	return nil
+}
@@ -0,0 +1,68 @@
+package record
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTerraformLog(t *testing.T) {
+	f, err := os.Open("../../testdata/log/terraform.log")
+	require.NoError(t, err)
+	defer f.Close()
+	records, err := ReadTerraformLog(f)
+	require.NoError(t, err)
+	require.Equal(t, []Record{
+		{
+			Method:       "PUT",
+			URL:          "https://management.azure.com/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15",
+			RequestBody:  json.RawMessage(`{"location":"westus"}`),
+			StatusCode:   201,
+			ResponseBody: json.RawMessage(`{"id":"/providers/Microsoft.Dummy/foos/foo1","location":"westus"}`),
+			ResponseHeaders: http.Header{
+				"Content-Length":       []string{"67"},
+				"Azure-Asyncoperation": []string{"https://management.azure.com/providers/Microsoft.Dummy/locations/westus/operationStatuses/op1?api-version=2023-05-15"},
+				"Cache-Control":        []string{"no-cache"},
+				"Content-Type":         []string{"application/json; charset=utf-8"},
+			},
+		},
+		{
+			Method:       "GET",
+			URL:          "https://management.azure.com/providers/Microsoft.Dummy/foos?api-version=2023-05-15",
+			StatusCode:   200,
+			ResponseBody: json.RawMessage(`{"value": []}`),
+			ResponseHeaders: http.Header{
+				"Content-Length": []string{"13"},
+				"Cache-Control":  []string{"no-cache"},
+				"Content-Type":   []string{"application/json; charset=utf-8"},
+			},
+		},
+		{
+			Method:      "PUT",
+			URL:         "https://management.azure.com/providers/Microsoft.Dummy/foos/foo2?api-version=2023-05-15",
+			RequestBody: json.RawMessage(`{"location":"westus"}`),
+			StatusCode:  202,
+			ResponseHeaders: http.Header{
+				"Content-Length": []string{"0"},
+				"Location":       []string{"https://management.azure.com/providers/Microsoft.Dummy/locations/westus/operationResults/op2?api-version=2023-05-15"},
+			},
+		},
+		{
+			Method:       "GET",
+			URL:          "https://management.azure.com/providers/Microsoft.Dummy/locations/westus/operationStatuses/op1?api-version=2023-05-15",
+			StatusCode:   200,
+			ResponseBody: json.RawMessage(`{"status":"Succeeded"}`),
+			ResponseHeaders: http.Header{
+				"Content-Length": []string{"22"},
+				"Content-Type":   []string{"application/json; charset=utf-8"},
+			},
+		},
+		{
+			Method: "DELETE",
+			URL:    "https://management.azure.com/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15",
+		},
+	}, records)
+}
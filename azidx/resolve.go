@@ -0,0 +1,254 @@
+package azidx
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+)
+
+// ResolvedOperation is the dereferenced Swagger operation that an operation ref in the index points to.
+type ResolvedOperation struct {
+	// Ref is the operation ref, relative to the spec dir
+	Ref string `json:"ref"`
+	// Path is the API path defined in the Swagger, e.g. /subscriptions/{subscriptionId}/providers/Microsoft.Foo/foos/{fooName}
+	Path        string        `json:"path"`
+	Method      OperationKind `json:"method"`
+	OperationID string        `json:"operation_id,omitempty"`
+	Summary     string        `json:"summary,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Deprecated  bool          `json:"deprecated,omitempty"`
+	// Parameters contains both the path level and operation level parameters, with all the $ref expanded.
+	Parameters []spec.Parameter `json:"parameters,omitempty"`
+	// RequestBody is the schema of the body parameter, if any.
+	RequestBody *spec.Schema `json:"request_body,omitempty"`
+	// Responses is keyed by the status code, or "default".
+	Responses          map[string]spec.Response `json:"responses,omitempty"`
+	LongRunning        bool                     `json:"long_running,omitempty"`
+	LongRunningOptions map[string]interface{}   `json:"long_running_options,omitempty"`
+	Pageable           *Pageable                `json:"pageable,omitempty"`
+	// Examples maps the titles of the x-ms-examples to the example files, relative to the spec dir.
+	Examples map[string]string `json:"examples,omitempty"`
+
+	// specFile is the absolute path of the spec file, which is used to resolve the circular $ref that are not expanded.
+	specFile string
+}
+
+// Pageable represents the x-ms-pageable extension.
+type Pageable struct {
+	NextLinkName  string `json:"next_link_name,omitempty"`
+	ItemName      string `json:"item_name,omitempty"`
+	OperationName string `json:"operation_name,omitempty"`
+}
+
+// ResolveSchema resolves the schema of the operation if it is a $ref, which are the circular references that are left unexpanded.
+// It returns nil if the $ref can't be resolved.
+func (op ResolvedOperation) ResolveSchema(sch *spec.Schema) *spec.Schema {
+	return resolveSchema(op.specFile, sch)
+}
+
+// StatusCodes returns the status codes (or "default") declared in the responses, in order.
+func (op ResolvedOperation) StatusCodes() []string {
+	var codes []string
+	for code := range op.Responses {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// ResolveOperation loads the Swagger spec that the operation ref points to, and returns the operation with all the $ref expanded.
+// The ref is relative to the specdir, as is recorded in the index.
+func ResolveOperation(specdir string, ref jsonreference.Ref) (*ResolvedOperation, error) {
+	specdir, err := filepath.Abs(specdir)
+	if err != nil {
+		return nil, err
+	}
+	specFile := filepath.Join(specdir, ref.GetURL().Path)
+
+	tks := ref.GetPointer().DecodedTokens()
+	if len(tks) != 3 || tks[0] != "paths" {
+		return nil, fmt.Errorf("ref %s doesn't point to an operation", ref.String())
+	}
+	path, method := tks[1], OperationKind(strings.ToUpper(tks[2]))
+
+	doc, err := loads.Spec(specFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading spec %s: %v", specFile, err)
+	}
+	swagger := doc.Spec()
+	if swagger.Paths == nil {
+		return nil, fmt.Errorf(`spec %s has no "paths"`, specFile)
+	}
+	pathItem, ok := swagger.Paths.Paths[path]
+	if !ok {
+		return nil, fmt.Errorf("spec %s has no path %s", specFile, path)
+	}
+	op := PathItemOperation(pathItem, method)
+	if op == nil {
+		return nil, fmt.Errorf("spec %s has no %s operation for path %s", specFile, method, path)
+	}
+
+	rop := &ResolvedOperation{
+		Ref:         ref.String(),
+		Path:        path,
+		Method:      method,
+		OperationID: op.ID,
+		Summary:     op.Summary,
+		Description: op.Description,
+		Deprecated:  op.Deprecated,
+		specFile:    specFile,
+	}
+
+	// Per operation parameter overrides the per path parameter
+	var params []spec.Parameter
+	paramIdx := map[string]int{}
+	for _, l := range [][]spec.Parameter{pathItem.Parameters, op.Parameters} {
+		for _, param := range l {
+			param := param
+			if err := spec.ExpandParameter(&param, specFile); err != nil {
+				return nil, fmt.Errorf("expanding parameter %q: %v", param.Ref.String(), err)
+			}
+			key := param.In + ":" + param.Name
+			if idx, ok := paramIdx[key]; ok {
+				params[idx] = param
+				continue
+			}
+			paramIdx[key] = len(params)
+			params = append(params, param)
+		}
+	}
+	rop.Parameters = params
+	for _, param := range params {
+		if param.In == "body" {
+			rop.RequestBody = param.Schema
+			break
+		}
+	}
+
+	if op.Responses != nil {
+		rop.Responses = map[string]spec.Response{}
+		if resp := op.Responses.Default; resp != nil {
+			resp := *resp
+			if err := spec.ExpandResponse(&resp, specFile); err != nil {
+				return nil, fmt.Errorf("expanding default response: %v", err)
+			}
+			rop.Responses["default"] = resp
+		}
+		for code, resp := range op.Responses.StatusCodeResponses {
+			resp := resp
+			if err := spec.ExpandResponse(&resp, specFile); err != nil {
+				return nil, fmt.Errorf("expanding response %d: %v", code, err)
+			}
+			rop.Responses[strconv.Itoa(code)] = resp
+		}
+	}
+
+	if v, ok := op.Extensions.GetBool("x-ms-long-running-operation"); ok {
+		rop.LongRunning = v
+	}
+	if v, ok := op.Extensions["x-ms-long-running-operation-options"]; ok {
+		if m, ok := v.(map[string]interface{}); ok {
+			rop.LongRunningOptions = m
+		}
+	}
+	pageable, err := parsePageable(op.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	rop.Pageable = pageable
+
+	rop.Examples = parseExamples(op.Extensions, filepath.Dir(ref.GetURL().Path))
+
+	return rop, nil
+}
+
+// parsePageable parses the x-ms-pageable extension, it returns nil if the extension is absent.
+func parsePageable(ext spec.Extensions) (*Pageable, error) {
+	v, ok := ext["x-ms-pageable"]
+	if !ok {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected type of x-ms-pageable: %T", v)
+	}
+	var pageable Pageable
+	// nextLinkName can be null, which indicates there is only one page
+	if v, ok := m["nextLinkName"].(string); ok {
+		pageable.NextLinkName = v
+	}
+	if v, ok := m["itemName"].(string); ok {
+		pageable.ItemName = v
+	}
+	if v, ok := m["operationName"].(string); ok {
+		pageable.OperationName = v
+	}
+	return &pageable, nil
+}
+
+// parseExamples parses the x-ms-examples extension into the titles mapped to the example files, which are relative to the spec dir.
+// The specFileDir is the dir of the spec file, relative to the spec dir. It returns nil if the extension is absent.
+// The malformed entries are skipped, so that they don't fail resolving the operation.
+func parseExamples(ext spec.Extensions, specFileDir string) map[string]string {
+	v, ok := ext["x-ms-examples"]
+	if !ok {
+		return nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		logger.Debug("unexpected type of x-ms-examples", "type", fmt.Sprintf("%T", v))
+		return nil
+	}
+	examples := map[string]string{}
+	for title, v := range m {
+		entry, ok := v.(map[string]interface{})
+		if !ok {
+			logger.Debug("skip malformed x-ms-examples", "title", title)
+			continue
+		}
+		ref, ok := entry["$ref"].(string)
+		if !ok {
+			logger.Debug("skip x-ms-examples that has no $ref", "title", title)
+			continue
+		}
+		examples[title] = filepath.Join(specFileDir, ref)
+	}
+	return examples
+}
+
+// OperationResolver resolves operation refs with the resolved operations cached, which is useful when resolving a bunch of refs.
+type OperationResolver struct {
+	specdir string
+
+	mu    sync.Mutex
+	cache map[string]*ResolvedOperation
+}
+
+func NewOperationResolver(specdir string) *OperationResolver {
+	return &OperationResolver{
+		specdir: specdir,
+		cache:   map[string]*ResolvedOperation{},
+	}
+}
+
+// Resolve resolves the operation ref. See ResolveOperation for details.
+func (r *OperationResolver) Resolve(ref jsonreference.Ref) (*ResolvedOperation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if op, ok := r.cache[ref.String()]; ok {
+		return op, nil
+	}
+	op, err := ResolveOperation(r.specdir, ref)
+	if err != nil {
+		return nil, err
+	}
+	r.cache[ref.String()] = op
+	return op, nil
+}
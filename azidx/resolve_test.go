@@ -0,0 +1,72 @@
+package azidx
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/go-openapi/spec"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveOperation(t *testing.T) {
+	specRoot := "../testdata/spec"
+
+	ref := jsonreference.MustCreateRef("dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1{fooName}/put")
+	op, err := ResolveOperation(specRoot, ref)
+	require.NoError(t, err)
+	require.Equal(t, "/providers/Microsoft.Dummy/foos/{fooName}", op.Path)
+	require.Equal(t, OperationKind(OperationKindPut), op.Method)
+	require.Equal(t, "Foos_CreateOrUpdate", op.OperationID)
+	require.True(t, op.LongRunning)
+	require.Equal(t, map[string]interface{}{"final-state-via": "azure-async-operation"}, op.LongRunningOptions)
+	require.Nil(t, op.Pageable)
+	require.Equal(t, map[string]string{
+		"Create a foo": "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/examples/Foos_CreateOrUpdate.json",
+	}, op.Examples)
+
+	var paramNames []string
+	for _, param := range op.Parameters {
+		paramNames = append(paramNames, param.Name)
+	}
+	require.Equal(t, []string{"fooName", "api-version", "body"}, paramNames)
+
+	// The body schema is expanded, including the one from another file
+	require.NotNil(t, op.RequestBody)
+	require.Contains(t, op.RequestBody.Properties, "location")
+	require.Contains(t, op.RequestBody.Properties["properties"].Properties, "size")
+	require.Len(t, op.RequestBody.AllOf, 1)
+	require.Contains(t, op.RequestBody.AllOf[0].Properties, "id")
+
+	require.Equal(t, []string{"200", "201", "default"}, op.StatusCodes())
+	require.Contains(t, op.Responses["default"].Schema.Properties, "error")
+
+	ref = jsonreference.MustCreateRef("dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos/get")
+	op, err = ResolveOperation(specRoot, ref)
+	require.NoError(t, err)
+	require.Equal(t, "Foos_List", op.OperationID)
+	require.Equal(t, &Pageable{NextLinkName: "nextLink"}, op.Pageable)
+	require.Contains(t, op.Responses["200"].Schema.Properties["value"].Items.Schema.Properties, "properties")
+
+	ref = jsonreference.MustCreateRef("dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos/put")
+	_, err = ResolveOperation(specRoot, ref)
+	require.Error(t, err)
+
+	ref = jsonreference.MustCreateRef("dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/definitions/Foo")
+	_, err = ResolveOperation(specRoot, ref)
+	require.Error(t, err)
+}
+
+func TestParseExamples(t *testing.T) {
+	require.Nil(t, parseExamples(spec.Extensions{}, "rp"))
+	require.Nil(t, parseExamples(spec.Extensions{"x-ms-examples": "foo"}, "rp"))
+	require.Equal(t, map[string]string{
+		"good": filepath.Join("rp", "examples", "good.json"),
+	}, parseExamples(spec.Extensions{
+		"x-ms-examples": map[string]interface{}{
+			"good":      map[string]interface{}{"$ref": "./examples/good.json"},
+			"not ref":   map[string]interface{}{"$ref": 1},
+			"no object": "./examples/bad.json",
+		},
+	}, "rp"))
+}
@@ -0,0 +1,304 @@
+package azidx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// ShardManifestFile is the name of the manifest file in the shard dir.
+const ShardManifestFile = "manifest.json"
+
+// ShardManifest describes the shards of an index, where each shard is an index file that contains only one RP.
+type ShardManifest struct {
+	FormatVersion int           `json:"format_version"`
+	Commit        string        `json:"commit,omitempty"`
+	Encoding      IndexEncoding `json:"encoding"`
+	// Shards maps the (upper cased) RP names, including the wildcard RP "*", to the shard file names, relative to the shard dir.
+	Shards map[string]string `json:"shards"`
+}
+
+// WriteShards writes the index as one shard file per RP in the encoding, together with the manifest file, to the dir.
+// If the dir already has the shards, the shard files of the existing manifest that are not rewritten are removed, e.g. of the RPs that no longer exist, or of another encoding.
+// The other files in the dir are left untouched.
+func WriteShards(dir string, idx *Index, encoding IndexEncoding) error {
+	var ext string
+	switch encoding {
+	case IndexEncodingJSON, "":
+		encoding = IndexEncodingJSON
+		ext = ".json"
+	case IndexEncodingGzip:
+		ext = ".json.gz"
+	case IndexEncodingBinary:
+		ext = ".bin"
+	default:
+		return fmt.Errorf("unknown index encoding %q", encoding)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var stale []string
+	if b, err := os.ReadFile(filepath.Join(dir, ShardManifestFile)); err == nil {
+		var existing ShardManifest
+		if err := json.Unmarshal(b, &existing); err != nil {
+			return fmt.Errorf("unmarshal the existing manifest: %v", err)
+		}
+		for _, name := range existing.Shards {
+			stale = append(stale, name)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("reading the existing manifest: %v", err)
+	}
+
+	manifest := ShardManifest{
+		FormatVersion: idx.FormatVersion,
+		Commit:        idx.Commit,
+		Encoding:      encoding,
+		Shards:        map[string]string{},
+	}
+	for rp := range idx.ResourceProviders {
+		shard := idx.shard(rp)
+		var buf bytes.Buffer
+		if err := EncodeIndex(&buf, shard, encoding); err != nil {
+			return fmt.Errorf("encoding shard of %q: %v", rp, err)
+		}
+		name := shardFileName(rp) + ext
+		if err := os.WriteFile(filepath.Join(dir, name), buf.Bytes(), 0644); err != nil {
+			return err
+		}
+		manifest.Shards[rp] = name
+	}
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ShardManifestFile), b, 0644); err != nil {
+		return err
+	}
+
+	written := map[string]bool{}
+	for _, name := range manifest.Shards {
+		written[name] = true
+	}
+	for _, name := range stale {
+		// Only the file directly in the dir is regarded as a shard file
+		if written[name] || filepath.Base(name) != name {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, name)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing the stale shard %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// shardFileName returns the file name (without extension) of the shard of the RP.
+func shardFileName(rp string) string {
+	switch rp {
+	case Wildcard:
+		return "_wildcard"
+	case "":
+		return "_none"
+	}
+	return strings.ToLower(rp)
+}
+
+// shard returns the index that only contains the RP, and the metadata of its operations.
+func (idx Index) shard(rp string) *Index {
+	shard := &Index{
+		FormatVersion:     idx.FormatVersion,
+		Commit:            idx.Commit,
+		ResourceProviders: ResourceProviders{rp: idx.ResourceProviders[rp]},
+	}
+	if idx.Operations != nil {
+		shard.Operations = map[string]*OperationMetadata{}
+		add := func(oprefs OperationRefs) {
+			for _, ref := range oprefs {
+				if meta, ok := idx.Operations[ref.String()]; ok {
+					shard.Operations[ref.String()] = meta
+				}
+			}
+		}
+		for _, methods := range idx.ResourceProviders[rp] {
+			for _, rts := range methods {
+				for _, info := range rts {
+					add(info.OperationRefs)
+					for _, oprefs := range info.Actions {
+						add(oprefs)
+					}
+				}
+			}
+		}
+	}
+	return shard
+}
+
+// ShardedIndex is the index that is split into shards by RP, which are only loaded when needed.
+// It is safe for concurrent use.
+type ShardedIndex struct {
+	dir      string
+	manifest ShardManifest
+
+	mu     sync.Mutex
+	shards map[string]*Index
+	// lookupIndexes caches the index for looking up into an RP, which consists of the RP and the wildcard RP.
+	lookupIndexes map[string]*Index
+}
+
+// OpenShardedIndex reads the manifest file of the shard dir, without loading any shard.
+func OpenShardedIndex(dir string) (*ShardedIndex, error) {
+	b, err := os.ReadFile(filepath.Join(dir, ShardManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("reading the manifest: %v", err)
+	}
+	var manifest ShardManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("unmarshal the manifest: %v", err)
+	}
+	if manifest.FormatVersion > IndexFormatVersion {
+		return nil, fmt.Errorf("the index format version %d is newer than the supported version %d, please upgrade this tool", manifest.FormatVersion, IndexFormatVersion)
+	}
+	return &ShardedIndex{
+		dir:           dir,
+		manifest:      manifest,
+		shards:        map[string]*Index{},
+		lookupIndexes: map[string]*Index{},
+	}, nil
+}
+
+// Manifest returns the manifest of the shards.
+func (s *ShardedIndex) Manifest() ShardManifest {
+	return s.manifest
+}
+
+// loadShard loads the shard of the RP, or returns nil if there is no such shard. The lock is expected to be held.
+func (s *ShardedIndex) loadShard(rp string) (*Index, error) {
+	if shard, ok := s.shards[rp]; ok {
+		return shard, nil
+	}
+	name, ok := s.manifest.Shards[rp]
+	if !ok {
+		return nil, nil
+	}
+	b, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("reading shard %s: %v", name, err)
+	}
+	shard, err := DecodeIndex(b)
+	if err != nil {
+		return nil, fmt.Errorf("loading shard %s: %v", name, err)
+	}
+	if err := shard.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid shard %s: %v", name, err)
+	}
+	s.shards[rp] = shard
+	return shard, nil
+}
+
+// lookupIndex returns the index that consists of the RP and the wildcard RP, with the shards loaded if needed.
+func (s *ShardedIndex) lookupIndex(rp string) (*Index, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The RP that has no shard is looked up into the wildcard RP only
+	if _, ok := s.manifest.Shards[rp]; !ok {
+		rp = Wildcard
+	}
+	if idx, ok := s.lookupIndexes[rp]; ok {
+		return idx, nil
+	}
+	idx := &Index{
+		FormatVersion:     IndexFormatVersion,
+		Commit:            s.manifest.Commit,
+		ResourceProviders: ResourceProviders{},
+	}
+	for _, name := range []string{rp, Wildcard} {
+		shard, err := s.loadShard(name)
+		if err != nil {
+			return nil, err
+		}
+		if shard != nil {
+			idx.merge(shard)
+		}
+	}
+	idx.buildLookupMatchers()
+	s.lookupIndexes[rp] = idx
+	return idx, nil
+}
+
+// LookupOperation looks up the request, with only the shards of the request's RP and the wildcard RP loaded. See Index.LookupOperation.
+func (s *ShardedIndex) LookupOperation(method string, uRL url.URL) (*LookupResult, error) {
+	// The RP is left empty for the invalid request path, which is reported by the lookup
+	_, rp, _, _, _ := parseRequestPath(uRL)
+	idx, err := s.lookupIndex(rp)
+	if err != nil {
+		return nil, err
+	}
+	return idx.LookupOperation(method, uRL)
+}
+
+// LoadAll loads all the shards as one index, which is ready for looking up as the one loaded by LoadIndex.
+func (s *ShardedIndex) LoadAll() (*Index, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	idx := &Index{
+		FormatVersion:     IndexFormatVersion,
+		Commit:            s.manifest.Commit,
+		ResourceProviders: ResourceProviders{},
+	}
+	for rp := range s.manifest.Shards {
+		shard, err := s.loadShard(rp)
+		if err != nil {
+			return nil, err
+		}
+		idx.merge(shard)
+	}
+	idx.buildLookupMatchers()
+	return idx, nil
+}
+
+// ReadShardsAsIs reads all the shards of the shard dir as one index, together with the encoding of the shards.
+// Like DecodeIndexAsIs, the format version is left as is, for migrating the index with the specdir.
+func ReadShardsAsIs(dir string) (*Index, IndexEncoding, error) {
+	s, err := OpenShardedIndex(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	idx := &Index{
+		FormatVersion:     s.manifest.FormatVersion,
+		Commit:            s.manifest.Commit,
+		ResourceProviders: ResourceProviders{},
+	}
+	for _, name := range s.manifest.Shards {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return nil, "", fmt.Errorf("reading shard %s: %v", name, err)
+		}
+		shard, _, err := DecodeIndexAsIs(b)
+		if err != nil {
+			return nil, "", fmt.Errorf("loading shard %s: %v", name, err)
+		}
+		idx.merge(shard)
+	}
+	return idx, s.manifest.Encoding, nil
+}
+
+// merge merges the RPs and operation metadata of the shard into the index.
+func (idx *Index) merge(shard *Index) {
+	for k, v := range shard.ResourceProviders {
+		idx.ResourceProviders[k] = v
+	}
+	if shard.Operations != nil {
+		if idx.Operations == nil {
+			idx.Operations = map[string]*OperationMetadata{}
+		}
+		for k, v := range shard.Operations {
+			idx.Operations[k] = v
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package azidx
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShardedIndex(t *testing.T) {
+	idx, err := BuildIndex("../testdata/spec", "", nil)
+	require.NoError(t, err)
+	// Add a wildcard RP, which is loaded for the lookup of every RP
+	idx.ResourceProviders[Wildcard] = APIVersions{}
+
+	dir := t.TempDir()
+	require.NoError(t, WriteShards(dir, idx, IndexEncodingBinary))
+
+	s, err := OpenShardedIndex(dir)
+	require.NoError(t, err)
+	require.Equal(t, ShardManifest{
+		FormatVersion: IndexFormatVersion,
+		Encoding:      IndexEncodingBinary,
+		Shards: map[string]string{
+			"*":               "_wildcard.bin",
+			"MICROSOFT.DUMMY": "microsoft.dummy.bin",
+		},
+	}, s.Manifest())
+	require.Empty(t, s.shards)
+
+	// Only the wildcard RP shard is loaded for an unknown RP
+	uRL, err := url.Parse("/providers/Microsoft.Unknown/foos/foo1?api-version=2023-05-15")
+	require.NoError(t, err)
+	_, err = s.LookupOperation("GET", *uRL)
+	require.ErrorContains(t, err, "matches nothing")
+	require.Len(t, s.shards, 1)
+	require.Contains(t, s.shards, Wildcard)
+
+	uRL, err = url.Parse("/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15")
+	require.NoError(t, err)
+	result, err := s.LookupOperation("GET", *uRL)
+	require.NoError(t, err)
+	expect, err := idx.LookupOperation("GET", *uRL)
+	require.NoError(t, err)
+	require.Equal(t, expect.Ref.String(), result.Ref.String())
+	require.Equal(t, expect.Names, result.Names)
+	require.Equal(t, expect.Metadata, result.Metadata)
+	require.Len(t, s.shards, 2)
+
+	all, err := s.LoadAll()
+	require.NoError(t, err)
+	b1, err := json.Marshal(idx)
+	require.NoError(t, err)
+	b2, err := json.Marshal(all)
+	require.NoError(t, err)
+	require.JSONEq(t, string(b1), string(b2))
+}
+
+func TestWriteShardsRemoveStale(t *testing.T) {
+	idx, err := BuildIndex("../testdata/spec", "", nil)
+	require.NoError(t, err)
+	idx.ResourceProviders[Wildcard] = APIVersions{}
+
+	dir := t.TempDir()
+	require.NoError(t, WriteShards(dir, idx, IndexEncodingBinary))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("foo"), 0644))
+
+	// Rewrite in another encoding, without the wildcard RP
+	delete(idx.ResourceProviders, Wildcard)
+	require.NoError(t, WriteShards(dir, idx, IndexEncodingJSON))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	require.Equal(t, []string{"README.md", ShardManifestFile, "microsoft.dummy.json"}, names)
+
+	s, err := OpenShardedIndex(dir)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"MICROSOFT.DUMMY": "microsoft.dummy.json"}, s.Manifest().Shards)
+}
+
+func TestReadShardsAsIs(t *testing.T) {
+	idx, err := BuildIndex("../testdata/spec", "", nil)
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, WriteShards(dir, idx, IndexEncodingGzip))
+
+	read, encoding, err := ReadShardsAsIs(dir)
+	require.NoError(t, err)
+	require.Equal(t, IndexEncodingGzip, encoding)
+	require.Equal(t, idx.FormatVersion, read.FormatVersion)
+	require.Equal(t, idx.ResourceProviders, read.ResourceProviders)
+	require.Equal(t, idx.Operations, read.Operations)
+}
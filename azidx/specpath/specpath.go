@@ -51,6 +51,11 @@ func SpecPathInfo(p string) (*Info, error) {
 	}, nil
 }
 
+// Subservices returns the optional sub-services after the RP, e.g. ["Compute", "Cloudservice"] for compute/resource-manager/Microsoft.Compute/Compute/Cloudservice/stable/2022-04-04/cloudService.json
+func (info Info) Subservices() []string {
+	return info.subservices
+}
+
 // ToPath returns the relative path of the spec file
 func (info Info) ToPath() string {
 	segs := []string{info.ResourceProvider, "resource-manager", info.ResourceProviderMS}
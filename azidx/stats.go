@@ -0,0 +1,152 @@
+package azidx
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// IndexStats is the statistics of an index.
+type IndexStats struct {
+	Commit string `json:"commit,omitempty"`
+	// ResourceProviders is the count of RPs, excluding the wildcard RP.
+	ResourceProviders int `json:"resource_providers"`
+	// APIVersions is the count of the API versions of every RP (i.e. the same API version of two RPs counts twice), excluding the wildcard RP.
+	APIVersions        int `json:"api_versions"`
+	StableAPIVersions  int `json:"stable_api_versions"`
+	PreviewAPIVersions int `json:"preview_api_versions"`
+	// Operations is the count of distinct operation refs.
+	Operations         int                   `json:"operations"`
+	OperationsByMethod map[OperationKind]int `json:"operations_by_method"`
+	// Actions is the count of distinct actions (e.g. POST .../listKeys), identified by the operation locator.
+	Actions      int `json:"actions"`
+	PathPatterns int `json:"path_patterns"`
+	// MultiSegmentPathPatterns is the count of path patterns containing a "{*}" segment (i.e. x-ms-skip-url-encoding).
+	MultiSegmentPathPatterns int `json:"multi_segment_path_patterns"`
+	// WildcardRPPathPatterns is the count of path patterns under the wildcard RP.
+	WildcardRPPathPatterns int `json:"wildcard_rp_path_patterns"`
+	// EnumExpandedPathPatterns is the count of path patterns that are expanded from an enum parameter, i.e. whose operation ref is shared by other path patterns.
+	EnumExpandedPathPatterns int `json:"enum_expanded_path_patterns"`
+	// RPs are the per RP statistics, ordered by the operation count descendingly.
+	RPs []RPStats `json:"rps"`
+}
+
+// RPStats is the statistics of an RP in the index.
+type RPStats struct {
+	Name               string `json:"name"`
+	APIVersions        int    `json:"api_versions"`
+	StableAPIVersions  int    `json:"stable_api_versions"`
+	PreviewAPIVersions int    `json:"preview_api_versions"`
+	Operations         int    `json:"operations"`
+}
+
+var stableAPIVersionRegexp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+
+// IsPreviewAPIVersion tells whether the API version is a preview one, i.e. it has a suffix after the date (e.g. 2020-01-01-preview, 2020-01-01-beta).
+func IsPreviewAPIVersion(version string) bool {
+	return !stableAPIVersionRegexp.MatchString(version)
+}
+
+// Stats counts the RPs, API versions, operations and path patterns of the index.
+func (idx Index) Stats() *IndexStats {
+	stats := &IndexStats{
+		Commit:             idx.Commit,
+		OperationsByMethod: map[OperationKind]int{},
+	}
+	refs := map[string]bool{}
+	patternsPerRef := map[string]map[PathPatternStr]bool{}
+	for _, rpName := range sortedKeys(idx.ResourceProviders) {
+		versions := idx.ResourceProviders[rpName]
+		rpStats := RPStats{Name: rpName}
+		rpRefs := map[string]bool{}
+		for version, methods := range versions {
+			rpStats.APIVersions++
+			if IsPreviewAPIVersion(version) {
+				rpStats.PreviewAPIVersions++
+			} else {
+				rpStats.StableAPIVersions++
+			}
+			for method, rts := range methods {
+				for _, info := range rts {
+					addRefs := func(oprefs OperationRefs) {
+						for ppattern, ref := range oprefs {
+							refStr := ref.String()
+							stats.PathPatterns++
+							if strings.Contains(string(ppattern), "{*}") {
+								stats.MultiSegmentPathPatterns++
+							}
+							if rpName == Wildcard {
+								stats.WildcardRPPathPatterns++
+							}
+							if patternsPerRef[refStr] == nil {
+								patternsPerRef[refStr] = map[PathPatternStr]bool{}
+							}
+							patternsPerRef[refStr][ppattern] = true
+							rpRefs[refStr] = true
+							if !refs[refStr] {
+								refs[refStr] = true
+								stats.OperationsByMethod[method]++
+							}
+						}
+					}
+					addRefs(info.OperationRefs)
+					for _, oprefs := range info.Actions {
+						stats.Actions++
+						addRefs(oprefs)
+					}
+				}
+			}
+		}
+		rpStats.Operations = len(rpRefs)
+		if rpName == Wildcard {
+			continue
+		}
+		stats.ResourceProviders++
+		stats.APIVersions += rpStats.APIVersions
+		stats.StableAPIVersions += rpStats.StableAPIVersions
+		stats.PreviewAPIVersions += rpStats.PreviewAPIVersions
+		stats.RPs = append(stats.RPs, rpStats)
+	}
+	stats.Operations = len(refs)
+	for _, patterns := range patternsPerRef {
+		if len(patterns) > 1 {
+			stats.EnumExpandedPathPatterns += len(patterns)
+		}
+	}
+	sort.SliceStable(stats.RPs, func(i, j int) bool { return stats.RPs[i].Operations > stats.RPs[j].Operations })
+	return stats
+}
+
+// Text renders the statistics in plain text, with at most top RPs listed. A non-positive top lists every RP.
+func (s IndexStats) Text(top int) string {
+	var sb strings.Builder
+	if s.Commit != "" {
+		fmt.Fprintf(&sb, "Commit: %s\n", s.Commit)
+	}
+	fmt.Fprintf(&sb, "Resource providers: %d\n", s.ResourceProviders)
+	fmt.Fprintf(&sb, "API versions: %d (stable: %d, preview: %d)\n", s.APIVersions, s.StableAPIVersions, s.PreviewAPIVersions)
+	fmt.Fprintf(&sb, "Operations: %d\n", s.Operations)
+	for _, method := range sortedKeys(s.OperationsByMethod) {
+		fmt.Fprintf(&sb, "  %s: %d\n", method, s.OperationsByMethod[method])
+	}
+	fmt.Fprintf(&sb, "Actions: %d\n", s.Actions)
+	fmt.Fprintf(&sb, "Path patterns: %d\n", s.PathPatterns)
+	fmt.Fprintf(&sb, "  with {*} segment: %d\n", s.MultiSegmentPathPatterns)
+	fmt.Fprintf(&sb, "  of the wildcard RP: %d\n", s.WildcardRPPathPatterns)
+	fmt.Fprintf(&sb, "  expanded from enum: %d\n", s.EnumExpandedPathPatterns)
+
+	rps := s.RPs
+	if top > 0 && len(rps) > top {
+		rps = rps[:top]
+	}
+	fmt.Fprintf(&sb, "Largest RPs by operation count:\n")
+	for _, rp := range rps {
+		name := rp.Name
+		if name == "" {
+			name = "(none)"
+		}
+		fmt.Fprintf(&sb, "  %s: %d operations, %d API versions (stable: %d, preview: %d)\n", name, rp.Operations, rp.APIVersions, rp.StableAPIVersions, rp.PreviewAPIVersions)
+	}
+	return sb.String()
+}
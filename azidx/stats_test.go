@@ -0,0 +1,95 @@
+package azidx
+
+import (
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex_Stats(t *testing.T) {
+	index := Index{
+		ResourceProviders: ResourceProviders{
+			"*": APIVersions{
+				"ver1": APIMethods{
+					"GET": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/{*}/PROVIDERS/{}/FOOS/{}": jsonreference.MustCreateRef("#P1"),
+							},
+						},
+					},
+				},
+			},
+			"RP1": APIVersions{
+				"2020-01-01": APIMethods{
+					"GET": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/PROVIDERS/RP1/FOOS/{}":      jsonreference.MustCreateRef("#P2"),
+								"/PROVIDERS/RP1/FOOS/DEFAULT": jsonreference.MustCreateRef("#P2"),
+							},
+						},
+					},
+					"POST": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							Actions: map[string]OperationRefs{
+								"LISTKEYS": {
+									"/PROVIDERS/RP1/FOOS/{}/LISTKEYS": jsonreference.MustCreateRef("#P3"),
+								},
+							},
+						},
+					},
+				},
+				"2021-01-01-preview": APIMethods{
+					"GET": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/PROVIDERS/RP1/FOOS/{}": jsonreference.MustCreateRef("#P4"),
+							},
+						},
+					},
+				},
+			},
+			"RP2": APIVersions{
+				"2020-01-01": APIMethods{
+					"PUT": ResourceTypes{
+						"/BARS": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/PROVIDERS/RP2/BARS/{}": jsonreference.MustCreateRef("#P5"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	require.Equal(t, &IndexStats{
+		ResourceProviders:  2,
+		APIVersions:        3,
+		StableAPIVersions:  2,
+		PreviewAPIVersions: 1,
+		Operations:         5,
+		OperationsByMethod: map[OperationKind]int{
+			"GET":  3,
+			"POST": 1,
+			"PUT":  1,
+		},
+		Actions:                  1,
+		PathPatterns:             6,
+		MultiSegmentPathPatterns: 1,
+		WildcardRPPathPatterns:   1,
+		EnumExpandedPathPatterns: 2,
+		RPs: []RPStats{
+			{Name: "RP1", APIVersions: 2, StableAPIVersions: 1, PreviewAPIVersions: 1, Operations: 3},
+			{Name: "RP2", APIVersions: 1, StableAPIVersions: 1, Operations: 1},
+		},
+	}, index.Stats())
+}
+
+func TestIsPreviewAPIVersion(t *testing.T) {
+	require.False(t, IsPreviewAPIVersion("2020-01-01"))
+	require.True(t, IsPreviewAPIVersion("2020-01-01-preview"))
+	require.True(t, IsPreviewAPIVersion("2020-01-01-beta"))
+}
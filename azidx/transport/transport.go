@@ -0,0 +1,164 @@
+// Package transport provides the http.RoundTripper that annotates the outgoing ARM requests with their Swagger operations by looking up the index.
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/magodo/azure-rest-api-index/azidx"
+	"github.com/magodo/azure-rest-api-index/azidx/record"
+)
+
+// Lookuper looks up the request in the index, which is implemented by both *azidx.Index and *azidx.ShardedIndex.
+type Lookuper interface {
+	LookupOperation(method string, uRL url.URL) (*azidx.LookupResult, error)
+}
+
+// UnmatchedPolicy decides what to do with the request that matches no operation in the index.
+type UnmatchedPolicy string
+
+const (
+	// UnmatchedIgnore sends the request as is, which is the default.
+	UnmatchedIgnore UnmatchedPolicy = "ignore"
+	// UnmatchedWarn logs a warning (to the azidx logger), and sends the request.
+	UnmatchedWarn UnmatchedPolicy = "warn"
+	// UnmatchedFail fails the request with an *UnmatchedError, without sending it.
+	UnmatchedFail UnmatchedPolicy = "fail"
+)
+
+// Annotation is the lookup result of a request.
+type Annotation struct {
+	Method string
+	URL    string
+	// Result is the matched operation, which is nil if the request matches nothing.
+	Result *azidx.LookupResult
+	// Err is the lookup error if the request matches nothing, see azidx.LookupError.
+	Err error
+}
+
+// Ref returns the JSON reference to the matched operation, or "" if the request matches nothing.
+func (a Annotation) Ref() string {
+	if a.Result == nil {
+		return ""
+	}
+	return a.Result.Ref.String()
+}
+
+// OperationID returns the operationId of the matched operation, or "" if the request matches nothing or the index has no operation metadata.
+func (a Annotation) OperationID() string {
+	if a.Result == nil || a.Result.Metadata == nil {
+		return ""
+	}
+	return a.Result.Metadata.OperationID
+}
+
+// PathPattern returns the path pattern of the matched operation, or "" if the request matches nothing.
+func (a Annotation) PathPattern() string {
+	if a.Result == nil {
+		return ""
+	}
+	return string(a.Result.PathPattern)
+}
+
+type annotationKey struct{}
+
+// AnnotationFromContext returns the annotation attached to the request context by the RoundTripper.
+// The request passed to the wrapped RoundTripper carries the annotation, which is also available via the http.Response.Request.
+func AnnotationFromContext(ctx context.Context) (*Annotation, bool) {
+	a, ok := ctx.Value(annotationKey{}).(*Annotation)
+	return a, ok
+}
+
+// UnmatchedError is the error of the request that is failed by UnmatchedFail.
+type UnmatchedError struct {
+	Annotation *Annotation
+}
+
+func (e *UnmatchedError) Error() string {
+	return fmt.Sprintf("request %s %s matches no spec: %v", e.Annotation.Method, e.Annotation.URL, e.Annotation.Err)
+}
+
+func (e *UnmatchedError) Unwrap() error {
+	return e.Annotation.Err
+}
+
+// Options are the options of the RoundTripper.
+type Options struct {
+	// Hosts are the ARM hosts, the requests to other hosts are sent as is without annotation. Defaults to record.DefaultARMHosts.
+	Hosts []string
+	// OnAnnotation is the optional callback that is called with each annotated request, before it is sent.
+	OnAnnotation func(req *http.Request, a *Annotation)
+	// Unmatched decides what to do with the request that matches nothing. Defaults to UnmatchedIgnore.
+	Unmatched UnmatchedPolicy
+}
+
+// RoundTripper annotates each ARM request by looking up the index, and then sends it via the wrapped RoundTripper.
+type RoundTripper struct {
+	next         http.RoundTripper
+	index        Lookuper
+	hosts        []string
+	onAnnotation func(req *http.Request, a *Annotation)
+	unmatched    UnmatchedPolicy
+}
+
+var _ http.RoundTripper = &RoundTripper{}
+
+// New returns the RoundTripper that wraps the next RoundTripper, which defaults to http.DefaultTransport if nil.
+func New(next http.RoundTripper, index Lookuper, opts Options) *RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	hosts := opts.Hosts
+	if len(hosts) == 0 {
+		hosts = record.DefaultARMHosts
+	}
+	unmatched := opts.Unmatched
+	if unmatched == "" {
+		unmatched = UnmatchedIgnore
+	}
+	return &RoundTripper{
+		next:         next,
+		index:        index,
+		hosts:        hosts,
+		onAnnotation: opts.OnAnnotation,
+		unmatched:    unmatched,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *RoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !slices.ContainsFunc(t.hosts, func(host string) bool { return strings.EqualFold(host, req.URL.Hostname()) }) {
+		return t.next.RoundTrip(req)
+	}
+
+	a := &Annotation{
+		Method: req.Method,
+		URL:    req.URL.String(),
+	}
+	a.Result, a.Err = t.index.LookupOperation(req.Method, *req.URL)
+
+	// The RoundTripper must not modify the request, so the annotation is attached to a shallow copy
+	req = req.WithContext(context.WithValue(req.Context(), annotationKey{}, a))
+	if t.onAnnotation != nil {
+		t.onAnnotation(req, a)
+	}
+
+	// The LRO polling request that has no explicit definition is not regarded as unmatched
+	if a.Err != nil && !errors.Is(a.Err, azidx.ErrLROPoll) {
+		switch t.unmatched {
+		case UnmatchedWarn:
+			azidx.GetLogger().Warn("request matches no spec", "method", a.Method, "url", a.URL, "error", a.Err)
+		case UnmatchedFail:
+			if req.Body != nil {
+				req.Body.Close()
+			}
+			return nil, &UnmatchedError{Annotation: a}
+		}
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,94 @@
+package transport
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/magodo/azure-rest-api-index/azidx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTripper(t *testing.T) {
+	index := &azidx.Index{
+		ResourceProviders: azidx.ResourceProviders{
+			"RP1": azidx.APIVersions{
+				"ver1": azidx.APIMethods{
+					"GET": azidx.ResourceTypes{
+						"/FOOS": &azidx.OperationInfo{
+							OperationRefs: azidx.OperationRefs{
+								"/PROVIDERS/RP1/FOOS/{}": jsonreference.MustCreateRef("rp1.json#/paths/~1providers~1rp1~1foos~1{name}/get"),
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var requests int
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// The annotation doesn't go beyond the process
+		_, ok := AnnotationFromContext(r.Context())
+		require.False(t, ok)
+		io.WriteString(w, "{}")
+	}))
+	defer upstream.Close()
+
+	var annotations []*Annotation
+	newClient := func(hosts []string, unmatched UnmatchedPolicy) *http.Client {
+		return &http.Client{
+			Transport: New(nil, index, Options{
+				Hosts: hosts,
+				OnAnnotation: func(req *http.Request, a *Annotation) {
+					annotations = append(annotations, a)
+				},
+				Unmatched: unmatched,
+			}),
+		}
+	}
+
+	client := newClient([]string{"127.0.0.1"}, UnmatchedFail)
+
+	resp, err := client.Get(upstream.URL + "/providers/rp1/foos/foo1?api-version=ver1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	a, ok := AnnotationFromContext(resp.Request.Context())
+	require.True(t, ok)
+	require.Equal(t, "rp1.json#/paths/~1providers~1rp1~1foos~1%7Bname%7D/get", a.Ref())
+	require.Equal(t, "/PROVIDERS/RP1/FOOS/{}", a.PathPattern())
+	require.Equal(t, "", a.OperationID())
+	require.NoError(t, a.Err)
+	require.Equal(t, 1, requests)
+
+	// The unmatched request fails without being sent
+	_, err = client.Get(upstream.URL + "/providers/rp1/bars/bar1?api-version=ver1")
+	var uerr *UnmatchedError
+	require.ErrorAs(t, err, &uerr)
+	require.True(t, errors.Is(err, azidx.ErrNoMatch))
+	require.Equal(t, 1, requests)
+	require.Len(t, annotations, 2)
+	require.Nil(t, annotations[1].Result)
+
+	// The unmatched request is sent for the other policies
+	client = newClient([]string{"127.0.0.1"}, UnmatchedWarn)
+	resp, err = client.Get(upstream.URL + "/providers/rp1/bars/bar1?api-version=ver1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, 2, requests)
+	require.Len(t, annotations, 3)
+
+	// The request to the non-ARM host is not annotated
+	client = newClient(nil, UnmatchedFail)
+	resp, err = client.Get(upstream.URL + "/providers/rp1/bars/bar1?api-version=ver1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, 3, requests)
+	require.Len(t, annotations, 3)
+	_, ok = AnnotationFromContext(resp.Request.Context())
+	require.False(t, ok)
+}
@@ -0,0 +1,168 @@
+package azidx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RTNode is a node of the resource type hierarchy of an RP.
+type RTNode struct {
+	// Name is the last segment of the resource type, e.g. SUBNETS, or "*". It is the upper cased RP name for the root node.
+	Name string `json:"name"`
+	// Type is the resource type key in the index, e.g. /VIRTUALNETWORKS/SUBNETS. It is "/" for the root node, which represents the RP itself.
+	Type string `json:"type"`
+	// Methods are the methods available on the resource type (excluding the actions).
+	Methods []OperationKind `json:"methods,omitempty"`
+	// Actions are the actions (or collection types) available on the resource type.
+	Actions []RTAction `json:"actions,omitempty"`
+	// APIVersions are the API versions that define any operation of the resource type, sorted.
+	APIVersions []string  `json:"api_versions,omitempty"`
+	Children    []*RTNode `json:"children,omitempty"`
+}
+
+type RTAction struct {
+	Method OperationKind `json:"method"`
+	Name   string        `json:"name"`
+}
+
+// ResourceTypeTree builds the resource type hierarchy of the RP (case insensitive). It returns nil if the RP is not in the index.
+// Intermediate resource types that have no operation defined are also present in the tree, with no methods, actions or API versions.
+func (idx Index) ResourceTypeTree(rp string) *RTNode {
+	rp = strings.ToUpper(rp)
+	versions, ok := idx.ResourceProviders[rp]
+	if !ok {
+		return nil
+	}
+
+	type nodeBuilder struct {
+		node     *RTNode
+		methods  map[OperationKind]bool
+		actions  map[RTAction]bool
+		versions map[string]bool
+		children map[string]*nodeBuilder
+	}
+	newBuilder := func(name, typ string) *nodeBuilder {
+		return &nodeBuilder{
+			node:     &RTNode{Name: name, Type: typ},
+			methods:  map[OperationKind]bool{},
+			actions:  map[RTAction]bool{},
+			versions: map[string]bool{},
+			children: map[string]*nodeBuilder{},
+		}
+	}
+	root := newBuilder(rp, "/")
+	for version, methods := range versions {
+		for method, rts := range methods {
+			for rt, info := range rts {
+				b := root
+				for _, seg := range strings.Split(strings.Trim(rt, "/"), "/") {
+					if seg == "" {
+						continue
+					}
+					child, ok := b.children[seg]
+					if !ok {
+						child = newBuilder(seg, strings.TrimSuffix(b.node.Type, "/")+"/"+seg)
+						b.children[seg] = child
+					}
+					b = child
+				}
+				b.versions[version] = true
+				if len(info.OperationRefs) != 0 {
+					b.methods[method] = true
+				}
+				for act := range info.Actions {
+					b.actions[RTAction{Method: method, Name: act}] = true
+				}
+			}
+		}
+	}
+
+	var build func(b *nodeBuilder) *RTNode
+	build = func(b *nodeBuilder) *RTNode {
+		node := b.node
+		node.Methods = sortedKeys(b.methods)
+		for act := range b.actions {
+			node.Actions = append(node.Actions, act)
+		}
+		sort.Slice(node.Actions, func(i, j int) bool {
+			if node.Actions[i].Name != node.Actions[j].Name {
+				return node.Actions[i].Name < node.Actions[j].Name
+			}
+			return node.Actions[i].Method < node.Actions[j].Method
+		})
+		node.APIVersions = sortedKeys(b.versions)
+		for _, name := range sortedKeys(b.children) {
+			node.Children = append(node.Children, build(b.children[name]))
+		}
+		return node
+	}
+	return build(root)
+}
+
+// Text renders the tree in indented text, one resource type per line.
+func (n *RTNode) Text() string {
+	var sb strings.Builder
+	var f func(n *RTNode, depth int)
+	f = func(n *RTNode, depth int) {
+		sb.WriteString(strings.Repeat("  ", depth) + n.Name)
+		if len(n.Methods) != 0 {
+			var methods []string
+			for _, m := range n.Methods {
+				methods = append(methods, string(m))
+			}
+			fmt.Fprintf(&sb, "  methods: %s", strings.Join(methods, ","))
+		}
+		if len(n.Actions) != 0 {
+			var actions []string
+			for _, act := range n.Actions {
+				actions = append(actions, string(act.Method)+" "+act.Name)
+			}
+			fmt.Fprintf(&sb, "  actions: %s", strings.Join(actions, ","))
+		}
+		if l := len(n.APIVersions); l != 0 {
+			fmt.Fprintf(&sb, "  versions: %s..%s (%d)", n.APIVersions[0], n.APIVersions[l-1], l)
+		}
+		sb.WriteString("\n")
+		for _, child := range n.Children {
+			f(child, depth+1)
+		}
+	}
+	f(n, 0)
+	return sb.String()
+}
+
+// DOT renders the trees of one or more RPs in the Graphviz DOT language.
+func DOT(trees []*RTNode) string {
+	var sb strings.Builder
+	sb.WriteString("digraph resource_types {\n")
+	sb.WriteString("  rankdir=LR;\n")
+	sb.WriteString("  node [shape=box];\n")
+	for _, root := range trees {
+		id := func(n *RTNode) string {
+			return fmt.Sprintf("%q", root.Name+n.Type)
+		}
+		var f func(n *RTNode)
+		f = func(n *RTNode) {
+			label := n.Name
+			if len(n.Methods) != 0 {
+				var methods []string
+				for _, m := range n.Methods {
+					methods = append(methods, string(m))
+				}
+				label += "\n" + strings.Join(methods, ",")
+			}
+			if len(n.Actions) != 0 {
+				label += fmt.Sprintf("\n%d action(s)", len(n.Actions))
+			}
+			fmt.Fprintf(&sb, "  %s [label=%q];\n", id(n), label)
+			for _, child := range n.Children {
+				f(child)
+				fmt.Fprintf(&sb, "  %s -> %s;\n", id(n), id(child))
+			}
+		}
+		f(root)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+package azidx
+
+import (
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex_ResourceTypeTree(t *testing.T) {
+	index := Index{
+		ResourceProviders: ResourceProviders{
+			"RP1": APIVersions{
+				"2020-01-01": APIMethods{
+					"GET": ResourceTypes{
+						"/": &OperationInfo{
+							Actions: map[string]OperationRefs{
+								"FOOS": {"/PROVIDERS/RP1/FOOS": jsonreference.MustCreateRef("#P1")},
+							},
+						},
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{"/PROVIDERS/RP1/FOOS/{}": jsonreference.MustCreateRef("#P2")},
+						},
+					},
+				},
+				"2021-01-01": APIMethods{
+					"PUT": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{"/PROVIDERS/RP1/FOOS/{}": jsonreference.MustCreateRef("#P3")},
+						},
+						"/FOOS/BARS/BAZS": &OperationInfo{
+							OperationRefs: OperationRefs{"/PROVIDERS/RP1/FOOS/{}/BARS/{}/BAZS/{}": jsonreference.MustCreateRef("#P4")},
+						},
+					},
+					"POST": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							Actions: map[string]OperationRefs{
+								"LISTKEYS": {"/PROVIDERS/RP1/FOOS/{}/LISTKEYS": jsonreference.MustCreateRef("#P5")},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	require.Nil(t, index.ResourceTypeTree("RP2"))
+
+	tree := index.ResourceTypeTree("rp1")
+	require.Equal(t, &RTNode{
+		Name:        "RP1",
+		Type:        "/",
+		Actions:     []RTAction{{Method: "GET", Name: "FOOS"}},
+		APIVersions: []string{"2020-01-01"},
+		Children: []*RTNode{
+			{
+				Name:        "FOOS",
+				Type:        "/FOOS",
+				Methods:     []OperationKind{"GET", "PUT"},
+				Actions:     []RTAction{{Method: "POST", Name: "LISTKEYS"}},
+				APIVersions: []string{"2020-01-01", "2021-01-01"},
+				Children: []*RTNode{
+					{
+						Name: "BARS",
+						Type: "/FOOS/BARS",
+						Children: []*RTNode{
+							{
+								Name:        "BAZS",
+								Type:        "/FOOS/BARS/BAZS",
+								Methods:     []OperationKind{"PUT"},
+								APIVersions: []string{"2021-01-01"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, tree)
+
+	require.Equal(t, `RP1  actions: GET FOOS  versions: 2020-01-01..2020-01-01 (1)
+  FOOS  methods: GET,PUT  actions: POST LISTKEYS  versions: 2020-01-01..2021-01-01 (2)
+    BARS
+      BAZS  methods: PUT  versions: 2021-01-01..2021-01-01 (1)
+`, tree.Text())
+
+	require.Contains(t, DOT([]*RTNode{tree}), `"RP1/FOOS" -> "RP1/FOOS/BARS";`)
+}
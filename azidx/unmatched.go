@@ -0,0 +1,155 @@
+package azidx
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// LookupFailureCause is the likely cause of a request matching nothing in the index.
+type LookupFailureCause string
+
+const (
+	// LookupFailureInvalidResourceID means the request path can't be parsed as an ARM resource id.
+	LookupFailureInvalidResourceID LookupFailureCause = "invalid_resource_id"
+	// LookupFailureUnknownRP means the RP is not in the index.
+	LookupFailureUnknownRP LookupFailureCause = "unknown_rp"
+	// LookupFailureAPIVersionNotFound means the RP is known, but the api-version is not.
+	LookupFailureAPIVersionNotFound LookupFailureCause = "api_version_not_found"
+	// LookupFailureMethodNotFound means the api-version is known, but has no operation of the method.
+	LookupFailureMethodNotFound LookupFailureCause = "method_not_found"
+	// LookupFailureRTUnmatched means no resource type of the method matches the request.
+	LookupFailureRTUnmatched LookupFailureCause = "rt_unmatched"
+	// LookupFailureActionUnmatched means the resource type matches, but the action doesn't.
+	LookupFailureActionUnmatched LookupFailureCause = "action_unmatched"
+	// LookupFailurePathPatternUnmatched means the resource type (and action) matches, but no path pattern does.
+	LookupFailurePathPatternUnmatched LookupFailureCause = "path_pattern_unmatched"
+)
+
+// rank returns how far the lookup has gone before failing.
+func (c LookupFailureCause) rank() int {
+	switch c {
+	case LookupFailureInvalidResourceID:
+		return 1
+	case LookupFailureUnknownRP:
+		return 2
+	case LookupFailureAPIVersionNotFound:
+		return 3
+	case LookupFailureMethodNotFound:
+		return 4
+	case LookupFailureRTUnmatched:
+		return 5
+	case LookupFailureActionUnmatched:
+		return 6
+	case LookupFailurePathPatternUnmatched:
+		return 7
+	}
+	return 0
+}
+
+// DefaultUnmatchedExamples is the default max count of example URLs kept for each group of the unmatched report.
+const DefaultUnmatchedExamples = 5
+
+// UnmatchedReport collects the requests that match nothing in the index, grouped by the likely cause.
+type UnmatchedReport struct {
+	index       *Index
+	maxExamples int
+	total       int
+	lroPolls    int
+	groups      map[LookupFailureCause]*UnmatchedGroup
+}
+
+// UnmatchedGroup is a group of the unmatched requests of the same cause.
+type UnmatchedGroup struct {
+	Cause LookupFailureCause `json:"cause"`
+	Count int                `json:"count"`
+	// Examples are the first (distinct) unmatched requests, in form of "<METHOD> <URL>".
+	Examples []string `json:"examples"`
+}
+
+// NewUnmatchedReport creates an unmatched report, which keeps at most maxExamples example requests per group.
+// A non-positive maxExamples means DefaultUnmatchedExamples.
+func NewUnmatchedReport(index *Index, maxExamples int) *UnmatchedReport {
+	if maxExamples <= 0 {
+		maxExamples = DefaultUnmatchedExamples
+	}
+	return &UnmatchedReport{
+		index:       index,
+		maxExamples: maxExamples,
+		groups:      map[LookupFailureCause]*UnmatchedGroup{},
+	}
+}
+
+// Record looks up the request, and records it if it matches nothing. The returned cause is "" if the request matches an operation.
+// The LRO polling request that matches nothing is counted separately, instead of as unmatched.
+func (r *UnmatchedReport) Record(method string, uRL url.URL) LookupFailureCause {
+	r.total++
+	_, cause, err := r.index.lookupOperation(method, uRL)
+	if cause == "" {
+		return ""
+	}
+	if errors.Is(err, ErrLROPoll) {
+		r.lroPolls++
+		return cause
+	}
+	group, ok := r.groups[cause]
+	if !ok {
+		group = &UnmatchedGroup{Cause: cause}
+		r.groups[cause] = group
+	}
+	group.Count++
+	example := strings.ToUpper(method) + " " + uRL.String()
+	if len(group.Examples) < r.maxExamples {
+		for _, e := range group.Examples {
+			if e == example {
+				return cause
+			}
+		}
+		group.Examples = append(group.Examples, example)
+	}
+	return cause
+}
+
+// UnmatchedSummary is the summary of the unmatched report.
+type UnmatchedSummary struct {
+	Total     int `json:"total"`
+	Unmatched int `json:"unmatched"`
+	// LROPolls is the count of the LRO polling requests that match nothing, which are not regarded as unmatched.
+	LROPolls int               `json:"lro_polls,omitempty"`
+	Groups   []*UnmatchedGroup `json:"groups"`
+}
+
+// Summary returns the groups ordered by the count descendingly.
+func (r *UnmatchedReport) Summary() UnmatchedSummary {
+	sum := UnmatchedSummary{Total: r.total, LROPolls: r.lroPolls}
+	for _, group := range r.groups {
+		sum.Unmatched += group.Count
+		sum.Groups = append(sum.Groups, group)
+	}
+	sort.Slice(sum.Groups, func(i, j int) bool {
+		gi, gj := sum.Groups[i], sum.Groups[j]
+		if gi.Count != gj.Count {
+			return gi.Count > gj.Count
+		}
+		return gi.Cause.rank() < gj.Cause.rank()
+	})
+	return sum
+}
+
+// String renders the summary in plain text.
+func (s UnmatchedSummary) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d of %d request(s) match nothing\n", s.Unmatched, s.Total)
+	if s.LROPolls != 0 {
+		fmt.Fprintf(&sb, "%d LRO polling request(s) excluded\n", s.LROPolls)
+	}
+	for _, group := range s.Groups {
+		fmt.Fprintf(&sb, "\n%s: %d\n", group.Cause, group.Count)
+		for _, e := range group.Examples {
+			fmt.Fprintf(&sb, "  %s\n", e)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,85 @@
+package azidx
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmatchedReport(t *testing.T) {
+	index := &Index{
+		ResourceProviders: ResourceProviders{
+			"*": APIVersions{
+				"ver1": APIMethods{
+					"GET": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/PROVIDERS/{}/FOOS/{}": jsonreference.MustCreateRef("#*:VER1:GET:/FOOS::P1"),
+							},
+						},
+					},
+				},
+			},
+			"RP1": APIVersions{
+				"ver1": APIMethods{
+					"GET": ResourceTypes{
+						"/FOOS": &OperationInfo{
+							OperationRefs: OperationRefs{
+								"/PROVIDERS/RP1/FOOS/{}": jsonreference.MustCreateRef("#RP1:VER1:GET:/FOOS::P1"),
+							},
+						},
+					},
+					"POST": ResourceTypes{
+						"/": &OperationInfo{
+							Actions: map[string]OperationRefs{
+								"ACT1": {
+									"/PROVIDERS/RP1/ACT1": jsonreference.MustCreateRef("#RP1:VER1:POST:/:ACT1:P1"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		method string
+		url    string
+		cause  LookupFailureCause
+	}{
+		{"GET", "/providers/rp1/foos/foo1?api-version=ver1", ""},
+		{"GET", "/foos/foo1?api-version=ver1", LookupFailureInvalidResourceID},
+		{"GET", "/providers/rp2/bars/bar1?api-version=ver1", LookupFailureUnknownRP},
+		{"GET", "/providers/rp1/foos/foo1?api-version=ver2", LookupFailureAPIVersionNotFound},
+		{"DELETE", "/providers/rp1/foos/foo1?api-version=ver1", LookupFailureMethodNotFound},
+		{"GET", "/providers/rp1/bars/bar1?api-version=ver1", LookupFailureRTUnmatched},
+		{"POST", "/providers/rp1/act2?api-version=ver1", LookupFailureActionUnmatched},
+		{"GET", "/subscriptions/sub1/providers/rp1/foos/foo1?api-version=ver1", LookupFailurePathPatternUnmatched},
+		{"GET", "/subscriptions/sub1/providers/rp1/foos/foo2?api-version=ver1", LookupFailurePathPatternUnmatched},
+		{"GET", "/subscriptions/sub1/providers/rp1/foos/foo2?api-version=ver1", LookupFailurePathPatternUnmatched},
+	}
+
+	report := NewUnmatchedReport(index, 0)
+	for _, tt := range cases {
+		uRL, err := url.Parse(tt.url)
+		require.NoError(t, err)
+		require.Equal(t, tt.cause, report.Record(tt.method, *uRL), "%s %s", tt.method, tt.url)
+	}
+
+	sum := report.Summary()
+	require.Equal(t, 10, sum.Total)
+	require.Equal(t, 9, sum.Unmatched)
+	require.Len(t, sum.Groups, 7)
+	require.Equal(t, &UnmatchedGroup{
+		Cause: LookupFailurePathPatternUnmatched,
+		Count: 3,
+		Examples: []string{
+			"GET /subscriptions/sub1/providers/rp1/foos/foo1?api-version=ver1",
+			"GET /subscriptions/sub1/providers/rp1/foos/foo2?api-version=ver1",
+		},
+	}, sum.Groups[0])
+	require.Equal(t, LookupFailureInvalidResourceID, sum.Groups[1].Cause)
+}
@@ -0,0 +1,379 @@
+package azidx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/jsonpointer"
+	"github.com/go-openapi/spec"
+)
+
+type ValidationErrorKind string
+
+const (
+	ValidationErrorUnknownProperty ValidationErrorKind = "unknown_property"
+	ValidationErrorTypeMismatch    ValidationErrorKind = "type_mismatch"
+	ValidationErrorMissingRequired ValidationErrorKind = "missing_required"
+	ValidationErrorReadOnly        ValidationErrorKind = "read_only"
+	ValidationErrorUnexpectedBody  ValidationErrorKind = "unexpected_body"
+	ValidationErrorUndeclaredCode  ValidationErrorKind = "undeclared_status_code"
+)
+
+// ValidationError represents a schema violation of a request or response body.
+type ValidationError struct {
+	// Pointer is the JSON pointer to the violating value in the body. The root of the body is "".
+	Pointer string              `json:"pointer"`
+	Kind    ValidationErrorKind `json:"kind"`
+	Message string              `json:"message"`
+}
+
+func (e ValidationError) String() string {
+	return fmt.Sprintf("%s: %s: %s", e.Pointer, e.Kind, e.Message)
+}
+
+// ValidationResult is the result of validating a recorded request (or response) against its swagger definition.
+type ValidationResult struct {
+	Ref         string            `json:"ref"`
+	OperationID string            `json:"operation_id,omitempty"`
+	Errors      []ValidationError `json:"errors,omitempty"`
+}
+
+// Validator validates the recorded requests against their swagger definitions, which are looked up by the index.
+type Validator struct {
+	index    *Index
+	resolver *OperationResolver
+}
+
+// NewValidator creates a validator by the index and the spec dir, where the commit of the spec dir has to be the same as the index.
+func NewValidator(index *Index, specdir string) *Validator {
+	return &Validator{
+		index:    index,
+		resolver: NewOperationResolver(specdir),
+	}
+}
+
+// ValidateRequest looks up the operation of the request, and validates the request body against the schema of its body parameter.
+func (v *Validator) ValidateRequest(method string, uRL url.URL, body []byte) (*ValidationResult, error) {
+	ref, err := v.index.Lookup(method, uRL)
+	if err != nil {
+		return nil, err
+	}
+	op, err := v.resolver.Resolve(*ref)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %v", ref.String(), err)
+	}
+	errs, err := ValidateRequestBody(op, body)
+	if err != nil {
+		return nil, err
+	}
+	return &ValidationResult{
+		Ref:         ref.String(),
+		OperationID: op.OperationID,
+		Errors:      errs,
+	}, nil
+}
+
+// ValidateResponse looks up the operation of the request, and validates the response body against the response schema of the status code.
+func (v *Validator) ValidateResponse(method string, uRL url.URL, statusCode int, body []byte) (*ValidationResult, error) {
+	ref, err := v.index.Lookup(method, uRL)
+	if err != nil {
+		return nil, err
+	}
+	op, err := v.resolver.Resolve(*ref)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %v", ref.String(), err)
+	}
+	errs, err := ValidateResponseBody(op, statusCode, body)
+	if err != nil {
+		return nil, err
+	}
+	return &ValidationResult{
+		Ref:         ref.String(),
+		OperationID: op.OperationID,
+		Errors:      errs,
+	}, nil
+}
+
+// ValidateRequestBody validates the request body against the schema of the body parameter of the operation.
+// It reports unknown properties, wrong types, missing required properties, and read only properties sent in a PUT or PATCH request.
+func ValidateRequestBody(op *ResolvedOperation, body []byte) ([]ValidationError, error) {
+	var bodyParam *spec.Parameter
+	for i, param := range op.Parameters {
+		if param.In == "body" {
+			bodyParam = &op.Parameters[i]
+			break
+		}
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if bodyParam != nil && bodyParam.Required {
+			return []ValidationError{{Kind: ValidationErrorMissingRequired, Message: "the request body is required"}}, nil
+		}
+		return nil, nil
+	}
+	if bodyParam == nil {
+		return []ValidationError{{Kind: ValidationErrorUnexpectedBody, Message: "the operation defines no body parameter"}}, nil
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("decoding the request body: %v", err)
+	}
+	v := &schemaValidator{
+		specFile:      op.specFile,
+		checkRequired: true,
+		checkReadOnly: op.Method == OperationKindPut || op.Method == OperationKindPatch,
+	}
+	v.validate(bodyParam.Schema, value, "")
+	return v.errs, nil
+}
+
+// ValidateResponseBody validates the response body against the response schema of the operation, which is picked by the status code, or the "default" response.
+// It reports the status code that is not declared, the properties that are not modeled, and wrong types.
+func ValidateResponseBody(op *ResolvedOperation, statusCode int, body []byte) ([]ValidationError, error) {
+	var errs []ValidationError
+	resp, ok := op.Responses[strconv.Itoa(statusCode)]
+	if !ok {
+		var hasDefault bool
+		resp, hasDefault = op.Responses["default"]
+		// The "default" response is meant for the error responses, a successful response is expected to be declared explicitly.
+		if !hasDefault || statusCode < 400 {
+			errs = append(errs, ValidationError{Kind: ValidationErrorUndeclaredCode, Message: fmt.Sprintf("status code %d is not declared", statusCode)})
+		}
+		if !hasDefault {
+			return errs, nil
+		}
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return errs, nil
+	}
+	if resp.Schema == nil {
+		return append(errs, ValidationError{Kind: ValidationErrorUnexpectedBody, Message: fmt.Sprintf("the response of status code %d defines no schema", statusCode)}), nil
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return nil, fmt.Errorf("decoding the response body: %v", err)
+	}
+	v := &schemaValidator{
+		specFile: op.specFile,
+	}
+	v.validate(resp.Schema, value, "")
+	return append(errs, v.errs...), nil
+}
+
+func decodeJSON(b []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+type schemaValidator struct {
+	specFile      string
+	checkRequired bool
+	checkReadOnly bool
+
+	errs []ValidationError
+}
+
+func (v *schemaValidator) addError(ptr string, kind ValidationErrorKind, format string, args ...interface{}) {
+	v.errs = append(v.errs, ValidationError{
+		Pointer: ptr,
+		Kind:    kind,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// resolve resolves the schema if it is a $ref, see resolveSchema.
+func (v *schemaValidator) resolve(sch *spec.Schema) *spec.Schema {
+	return resolveSchema(v.specFile, sch)
+}
+
+// resolveSchema resolves the schema against the spec file if it is a $ref, which are the circular references that are left unexpanded.
+// It returns nil if the $ref can't be resolved.
+func resolveSchema(specFile string, sch *spec.Schema) *spec.Schema {
+	for sch != nil && sch.Ref.String() != "" {
+		rsch, err := spec.ResolveRefWithBase(nil, &sch.Ref, &spec.ExpandOptions{RelativeBase: specFile})
+		if err != nil {
+			logger.Debug("failed to resolve schema", "ref", sch.Ref.String(), "error", err)
+			return nil
+		}
+		sch = rsch
+	}
+	return sch
+}
+
+// flatSchema is the schema with the allOf merged.
+type flatSchema struct {
+	types         []string
+	properties    map[string]*spec.Schema
+	required      []string
+	additional    *spec.SchemaOrBool
+	items         *spec.SchemaOrArray
+	discriminator bool
+}
+
+func (v *schemaValidator) flatten(sch *spec.Schema) *flatSchema {
+	fs := &flatSchema{properties: map[string]*spec.Schema{}}
+	var f func(sch *spec.Schema, depth int)
+	f = func(sch *spec.Schema, depth int) {
+		// Guard against the (unexpected) circular allOf
+		if depth > 32 {
+			return
+		}
+		sch = v.resolve(sch)
+		if sch == nil {
+			return
+		}
+		fs.types = append(fs.types, sch.Type...)
+		for k, prop := range sch.Properties {
+			prop := prop
+			fs.properties[k] = &prop
+		}
+		fs.required = append(fs.required, sch.Required...)
+		if sch.AdditionalProperties != nil {
+			fs.additional = sch.AdditionalProperties
+		}
+		if sch.Items != nil {
+			fs.items = sch.Items
+		}
+		if sch.Discriminator != "" {
+			fs.discriminator = true
+		}
+		for i := range sch.AllOf {
+			f(&sch.AllOf[i], depth+1)
+		}
+	}
+	f(sch, 0)
+	if len(fs.types) == 0 && (len(fs.properties) != 0 || fs.additional != nil) {
+		fs.types = []string{"object"}
+	}
+	return fs
+}
+
+func (v *schemaValidator) validate(sch *spec.Schema, value interface{}, ptr string) {
+	// Null is regarded as absent
+	if value == nil {
+		return
+	}
+	fs := v.flatten(sch)
+	if len(fs.types) == 0 {
+		return
+	}
+	typ := fs.types[0]
+	if !matchType(typ, value) {
+		v.addError(ptr, ValidationErrorTypeMismatch, "expect %s, got %s", typ, jsonTypeOf(value))
+		return
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		var keys []string
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			kptr := ptr + "/" + jsonpointer.Escape(k)
+			if prop, ok := fs.properties[k]; ok {
+				if v.checkReadOnly {
+					if prop := v.resolve(prop); prop != nil && prop.ReadOnly {
+						v.addError(kptr, ValidationErrorReadOnly, "property %q is read only", k)
+						continue
+					}
+				}
+				v.validate(prop, value[k], kptr)
+				continue
+			}
+			if fs.additional != nil {
+				if fs.additional.Schema != nil {
+					v.validate(fs.additional.Schema, value[k], kptr)
+					continue
+				}
+				if fs.additional.Allows {
+					continue
+				}
+			}
+			// The polymorphic properties are defined in the derived models, which are not known here.
+			if fs.discriminator {
+				continue
+			}
+			v.addError(kptr, ValidationErrorUnknownProperty, "property %q is not defined", k)
+		}
+		if v.checkRequired {
+			required := map[string]bool{}
+			for _, k := range fs.required {
+				if required[k] {
+					continue
+				}
+				required[k] = true
+				if _, ok := value[k]; !ok {
+					v.addError(ptr+"/"+jsonpointer.Escape(k), ValidationErrorMissingRequired, "property %q is required", k)
+				}
+			}
+		}
+	case []interface{}:
+		if fs.items == nil || fs.items.Schema == nil {
+			return
+		}
+		for i, elem := range value {
+			v.validate(fs.items.Schema, elem, fmt.Sprintf("%s/%d", ptr, i))
+		}
+	}
+}
+
+func matchType(typ string, value interface{}) bool {
+	switch typ {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		return !strings.ContainsAny(n.String(), ".eE")
+	}
+	return true
+}
+
+func jsonTypeOf(value interface{}) string {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case json.Number:
+		if strings.ContainsAny(value.String(), ".eE") {
+			return "number"
+		}
+		return "integer"
+	}
+	return "null"
+}
@@ -0,0 +1,204 @@
+package azidx
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRequestBody(t *testing.T) {
+	specRoot := "../testdata/spec"
+	putRef := jsonreference.MustCreateRef("dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1{fooName}/put")
+	putOp, err := ResolveOperation(specRoot, putRef)
+	require.NoError(t, err)
+	getRef := jsonreference.MustCreateRef("dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1{fooName}/get")
+	getOp, err := ResolveOperation(specRoot, getRef)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name   string
+		op     *ResolvedOperation
+		body   string
+		expect []ValidationError
+	}{
+		{
+			name: "valid body",
+			op:   putOp,
+			body: `{"location": "westus", "tags": {"a": "b"}, "properties": {"size": 1, "enabled": true, "zones": ["1"]}}`,
+		},
+		{
+			name:   "missing body",
+			op:     putOp,
+			expect: []ValidationError{{Kind: ValidationErrorMissingRequired, Message: "the request body is required"}},
+		},
+		{
+			name:   "unexpected body",
+			op:     getOp,
+			body:   `{}`,
+			expect: []ValidationError{{Kind: ValidationErrorUnexpectedBody, Message: "the operation defines no body parameter"}},
+		},
+		{
+			name: "null is regarded as absent",
+			op:   putOp,
+			body: `{"location": "westus", "properties": null}`,
+		},
+		{
+			name: "violations",
+			op:   putOp,
+			body: `{"id": "/foos/foo1", "tags": {"a": 1}, "properties": {"size": 1.5, "enabled": "true", "zones": ["1", 2], "provisioningState": "Succeeded", "unknown": 1}}`,
+			expect: []ValidationError{
+				{Pointer: "/id", Kind: ValidationErrorReadOnly, Message: `property "id" is read only`},
+				{Pointer: "/properties/enabled", Kind: ValidationErrorTypeMismatch, Message: "expect boolean, got string"},
+				{Pointer: "/properties/provisioningState", Kind: ValidationErrorReadOnly, Message: `property "provisioningState" is read only`},
+				{Pointer: "/properties/size", Kind: ValidationErrorTypeMismatch, Message: "expect integer, got number"},
+				{Pointer: "/properties/unknown", Kind: ValidationErrorUnknownProperty, Message: `property "unknown" is not defined`},
+				{Pointer: "/properties/zones/1", Kind: ValidationErrorTypeMismatch, Message: "expect string, got integer"},
+				{Pointer: "/tags/a", Kind: ValidationErrorTypeMismatch, Message: "expect string, got integer"},
+				{Pointer: "/location", Kind: ValidationErrorMissingRequired, Message: `property "location" is required`},
+			},
+		},
+		{
+			name: "wrong root type",
+			op:   putOp,
+			body: `[]`,
+			expect: []ValidationError{
+				{Pointer: "", Kind: ValidationErrorTypeMismatch, Message: "expect object, got array"},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := ValidateRequestBody(tt.op, []byte(tt.body))
+			require.NoError(t, err)
+			require.Equal(t, tt.expect, errs)
+		})
+	}
+}
+
+func TestValidateResponseBody(t *testing.T) {
+	specRoot := "../testdata/spec"
+	getRef := jsonreference.MustCreateRef("dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1{fooName}/get")
+	getOp, err := ResolveOperation(specRoot, getRef)
+	require.NoError(t, err)
+	deleteRef := jsonreference.MustCreateRef("dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1{fooName}/delete")
+	deleteOp, err := ResolveOperation(specRoot, deleteRef)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name       string
+		op         *ResolvedOperation
+		statusCode int
+		body       string
+		expect     []ValidationError
+	}{
+		{
+			name:       "valid response",
+			op:         getOp,
+			statusCode: 200,
+			body:       `{"id": "/foos/foo1", "name": "foo1", "location": "westus", "properties": {"provisioningState": "Succeeded"}}`,
+		},
+		{
+			name:       "missing required property is not reported",
+			op:         getOp,
+			statusCode: 200,
+			body:       `{"id": "/foos/foo1"}`,
+		},
+		{
+			name:       "violations",
+			op:         getOp,
+			statusCode: 200,
+			body:       `{"id": 1, "systemData": {}, "properties": {"zones": "1"}}`,
+			expect: []ValidationError{
+				{Pointer: "/id", Kind: ValidationErrorTypeMismatch, Message: "expect string, got integer"},
+				{Pointer: "/properties/zones", Kind: ValidationErrorTypeMismatch, Message: "expect array, got string"},
+				{Pointer: "/systemData", Kind: ValidationErrorUnknownProperty, Message: `property "systemData" is not defined`},
+			},
+		},
+		{
+			name:       "error response matches the default",
+			op:         getOp,
+			statusCode: 404,
+			body:       `{"error": {"code": "NotFound", "message": "not found"}}`,
+		},
+		{
+			name:       "undeclared successful status code",
+			op:         getOp,
+			statusCode: 202,
+			body:       `{"error": {"code": "NotFound"}}`,
+			expect: []ValidationError{
+				{Kind: ValidationErrorUndeclaredCode, Message: "status code 202 is not declared"},
+			},
+		},
+		{
+			name:       "undeclared status code without default",
+			op:         deleteOp,
+			statusCode: 202,
+			expect: []ValidationError{
+				{Kind: ValidationErrorUndeclaredCode, Message: "status code 202 is not declared"},
+			},
+		},
+		{
+			name:       "unexpected body",
+			op:         deleteOp,
+			statusCode: 200,
+			body:       `{}`,
+			expect: []ValidationError{
+				{Kind: ValidationErrorUnexpectedBody, Message: "the response of status code 200 defines no schema"},
+			},
+		},
+	}
+
+	for _, tt := range cases {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := ValidateResponseBody(tt.op, tt.statusCode, []byte(tt.body))
+			require.NoError(t, err)
+			require.Equal(t, tt.expect, errs)
+		})
+	}
+}
+
+func TestValidator_ValidateRequest(t *testing.T) {
+	specRoot := "../testdata/spec"
+	idx, err := BuildIndex(specRoot, "", nil)
+	require.NoError(t, err)
+	v := NewValidator(idx, specRoot)
+
+	uRL, err := url.Parse("https://management.azure.com/providers/Microsoft.Dummy/foos/foo1?api-version=2023-05-15")
+	require.NoError(t, err)
+	result, err := v.ValidateRequest("PUT", *uRL, []byte(`{"location": "westus", "foo": "bar"}`))
+	require.NoError(t, err)
+	require.Equal(t, &ValidationResult{
+		Ref:         "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos~1%7BfooName%7D/put",
+		OperationID: "Foos_CreateOrUpdate",
+		Errors: []ValidationError{
+			{Pointer: "/foo", Kind: ValidationErrorUnknownProperty, Message: `property "foo" is not defined`},
+		},
+	}, result)
+
+	uRL, err = url.Parse("https://management.azure.com/providers/Microsoft.Dummy/foos/foo1?api-version=2020-01-01")
+	require.NoError(t, err)
+	_, err = v.ValidateRequest("PUT", *uRL, []byte(`{"location": "westus"}`))
+	require.Error(t, err)
+}
+
+func TestValidator_ValidateResponse(t *testing.T) {
+	specRoot := "../testdata/spec"
+	idx, err := BuildIndex(specRoot, "", nil)
+	require.NoError(t, err)
+	v := NewValidator(idx, specRoot)
+
+	uRL, err := url.Parse("https://management.azure.com/providers/Microsoft.Dummy/foos?api-version=2023-05-15")
+	require.NoError(t, err)
+	result, err := v.ValidateResponse("GET", *uRL, 200, []byte(`{"value": [{"location": "westus", "foo": "bar"}]}`))
+	require.NoError(t, err)
+	require.Equal(t, &ValidationResult{
+		Ref:         "dummy/resource-manager/Microsoft.Dummy/stable/2023-05-15/foo.json#/paths/~1providers~1Microsoft.Dummy~1foos/get",
+		OperationID: "Foos_List",
+		Errors: []ValidationError{
+			{Pointer: "/value/0/foo", Kind: ValidationErrorUnknownProperty, Message: `property "foo" is not defined`},
+		},
+	}, result)
+}
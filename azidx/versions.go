@@ -0,0 +1,122 @@
+package azidx
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// VersionQuery specifies the operation whose supporting API versions are queried.
+type VersionQuery struct {
+	// RP is the resource provider, e.g. Microsoft.Network. It is case insensitive.
+	RP string
+	// RT is the resource type, e.g. virtualNetworks/subnets. It is case insensitive. Empty means the RP itself (i.e. "/").
+	RT string
+	// Method is the HTTP method, e.g. GET
+	Method OperationKind
+	// Action is the action or collection type, e.g. listKeys. It is case insensitive. Empty means no action.
+	Action string
+}
+
+// APIVersionSupport is an API version that supports the queried operation.
+type APIVersionSupport struct {
+	Version string `json:"version"`
+	Preview bool   `json:"preview,omitempty"`
+	// RT is the resource type key in the index that matches the query, which might contain "*".
+	RT string `json:"rt"`
+	// Action is the action key in the index that matches the query, which might be "*".
+	Action string `json:"action,omitempty"`
+	// Refs are the distinct operation refs, sorted.
+	Refs []string `json:"refs"`
+}
+
+// VersionPolicy decides which API version is regarded as the latest.
+type VersionPolicy string
+
+const (
+	VersionPolicyStable  VersionPolicy = "stable"
+	VersionPolicyPreview VersionPolicy = "preview"
+	VersionPolicyAny     VersionPolicy = "any"
+)
+
+// SupportedAPIVersions returns the API versions of the RP that support the operation, sorted by date ascendingly (the stable version goes before the preview versions of the same date).
+// The wildcard RP is not taken into consideration.
+func (idx Index) SupportedAPIVersions(q VersionQuery) []APIVersionSupport {
+	rt := "/" + strings.Trim(strings.ToUpper(q.RT), "/")
+	act := strings.ToUpper(q.Action)
+	method := OperationKind(strings.ToUpper(string(q.Method)))
+
+	var out []APIVersionSupport
+	for version, methods := range idx.ResourceProviders[strings.ToUpper(q.RP)] {
+		rtKey, actKey, oprefs, ok := matchOperationInfo(methods[method], rt, act)
+		if !ok {
+			continue
+		}
+		refSet := map[string]bool{}
+		for _, ref := range oprefs {
+			refSet[ref.String()] = true
+		}
+		out = append(out, APIVersionSupport{
+			Version: version,
+			Preview: IsPreviewAPIVersion(version),
+			RT:      rtKey,
+			Action:  actKey,
+			Refs:    sortedKeys(refSet),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out
+}
+
+// LatestAPIVersion returns the latest API version that is allowed by the policy, from the API versions returned by SupportedAPIVersions.
+// It returns nil if there is no such version.
+func LatestAPIVersion(versions []APIVersionSupport, policy VersionPolicy) (*APIVersionSupport, error) {
+	switch policy {
+	case VersionPolicyStable, VersionPolicyPreview, VersionPolicyAny:
+	default:
+		return nil, fmt.Errorf("unknown version policy %q", policy)
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		v := versions[i]
+		if (policy == VersionPolicyStable && v.Preview) || (policy == VersionPolicyPreview && !v.Preview) {
+			continue
+		}
+		return &v, nil
+	}
+	return nil, nil
+}
+
+// matchOperationInfo finds the most specific resource type (and action) that matches the upper cased rt and act.
+func matchOperationInfo(rts ResourceTypes, rt, act string) (rtKey, actKey string, oprefs OperationRefs, ok bool) {
+	type candidate struct {
+		rt      string
+		matcher Matcher
+	}
+	var candidates []candidate
+	for k := range rts {
+		candidates = append(candidates, candidate{rt: k, matcher: buildRTMatcher(k)})
+	}
+	// Sort the resource type matchers to match from the most specific to the most general
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].matcher.Less(candidates[j].matcher)
+	})
+	for _, c := range candidates {
+		if !c.matcher.Match(rt) {
+			continue
+		}
+		info := rts[c.rt]
+		if act == "" {
+			if len(info.OperationRefs) == 0 {
+				continue
+			}
+			return c.rt, "", info.OperationRefs, true
+		}
+		if oprefs, ok := info.Actions[act]; ok {
+			return c.rt, act, oprefs, true
+		}
+		if oprefs, ok := info.Actions[Wildcard]; ok {
+			return c.rt, Wildcard, oprefs, true
+		}
+	}
+	return "", "", nil, false
+}
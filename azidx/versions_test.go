@@ -0,0 +1,72 @@
+package azidx
+
+import (
+	"testing"
+
+	"github.com/go-openapi/jsonreference"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndex_SupportedAPIVersions(t *testing.T) {
+	getFoo := func(ref string) APIMethods {
+		return APIMethods{
+			"GET": ResourceTypes{
+				"/FOOS": &OperationInfo{
+					OperationRefs: OperationRefs{
+						"/PROVIDERS/RP1/FOOS/{}": jsonreference.MustCreateRef(ref),
+					},
+				},
+			},
+		}
+	}
+	index := Index{
+		ResourceProviders: ResourceProviders{
+			"RP1": APIVersions{
+				"2021-01-01":         getFoo("#V2"),
+				"2020-01-01":         getFoo("#V1"),
+				"2022-01-01-preview": getFoo("#V3"),
+				"2019-01-01": APIMethods{
+					"POST": ResourceTypes{
+						"/FOOS/*": &OperationInfo{
+							Actions: map[string]OperationRefs{
+								"*": {
+									"/PROVIDERS/RP1/FOOS/{}/{}/{}/{}": jsonreference.MustCreateRef("#V0"),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	versions := index.SupportedAPIVersions(VersionQuery{RP: "rp1", RT: "foos", Method: "get"})
+	require.Equal(t, []APIVersionSupport{
+		{Version: "2020-01-01", RT: "/FOOS", Refs: []string{"#V1"}},
+		{Version: "2021-01-01", RT: "/FOOS", Refs: []string{"#V2"}},
+		{Version: "2022-01-01-preview", Preview: true, RT: "/FOOS", Refs: []string{"#V3"}},
+	}, versions)
+
+	latest, err := LatestAPIVersion(versions, VersionPolicyStable)
+	require.NoError(t, err)
+	require.Equal(t, "2021-01-01", latest.Version)
+	latest, err = LatestAPIVersion(versions, VersionPolicyPreview)
+	require.NoError(t, err)
+	require.Equal(t, "2022-01-01-preview", latest.Version)
+	latest, err = LatestAPIVersion(versions, VersionPolicyAny)
+	require.NoError(t, err)
+	require.Equal(t, "2022-01-01-preview", latest.Version)
+	_, err = LatestAPIVersion(versions, "foo")
+	require.Error(t, err)
+
+	// The wildcard resource type and action
+	versions = index.SupportedAPIVersions(VersionQuery{RP: "RP1", RT: "/foos/bars", Method: "POST", Action: "restart"})
+	require.Equal(t, []APIVersionSupport{
+		{Version: "2019-01-01", RT: "/FOOS/*", Action: "*", Refs: []string{"#V0"}},
+	}, versions)
+	latest, err = LatestAPIVersion(versions, VersionPolicyPreview)
+	require.NoError(t, err)
+	require.Nil(t, latest)
+
+	require.Empty(t, index.SupportedAPIVersions(VersionQuery{RP: "RP1", RT: "bars", Method: "GET"}))
+}
@@ -1,17 +1,28 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/go-openapi/jsonpointer"
 	"github.com/go-openapi/jsonreference"
 	"github.com/magodo/azure-rest-api-index/azidx"
+	"github.com/magodo/azure-rest-api-index/azidx/export"
+	"github.com/magodo/azure-rest-api-index/azidx/mock"
+	"github.com/magodo/azure-rest-api-index/azidx/proxy"
+	"github.com/magodo/azure-rest-api-index/azidx/record"
 	"github.com/magodo/jsonpointerpos"
 
 	"github.com/hashicorp/go-hclog"
@@ -24,11 +35,53 @@ var (
 	flagOutput   string
 	flagDedup    string
 	flagServices cli.StringSlice
+	flagEncoding string
+
+	flagOperationMetadata bool
+	flagShardDir          string
+	flagWorkers           int
 
 	flagIndex   string
 	flagMethod  string
 	flagURL     string
 	flagSpecDir string
+
+	flagRef    string
+	flagFormat string
+
+	flagBody         string
+	flagInput        string
+	flagStatus       int
+	flagResponseBody string
+
+	flagInputFormat  string
+	flagOutputFormat string
+	flagHosts        cli.StringSlice
+
+	flagExercisedVersionsOnly bool
+
+	flagExamples     int
+	flagListExamples bool
+
+	flagTop int
+
+	flagRP     string
+	flagRT     string
+	flagAction string
+	flagPolicy string
+
+	flagColumns     cli.StringSlice
+	flagRPs         cli.StringSlice
+	flagAPIVersions cli.StringSlice
+	flagMethods     cli.StringSlice
+	flagRTs         cli.StringSlice
+	flagActions     cli.StringSlice
+	flagStability   string
+
+	flagUpstream string
+	flagListen   string
+	flagTLSCert  string
+	flagTLSKey   string
 )
 
 func main() {
@@ -70,6 +123,27 @@ func main() {
 						Usage:       `Only build index for a list of services (e.g. "compute")`,
 						Destination: &flagServices,
 					},
+					&cli.StringFlag{
+						Name:        "encoding",
+						Usage:       `The encoding of the index file (json | gzip | binary). The index file of any encoding can be loaded by other subcommands`,
+						Destination: &flagEncoding,
+						Value:       string(azidx.IndexEncodingJSON),
+					},
+					&cli.IntFlag{
+						Name:        "workers",
+						Usage:       `The count of the spec files parsed concurrently. Defaults to the count of CPUs`,
+						Destination: &flagWorkers,
+					},
+					&cli.BoolFlag{
+						Name:        "operation-metadata",
+						Usage:       `Record the operation metadata (e.g. operationId, long running, pageable) in the index, which makes the index larger`,
+						Destination: &flagOperationMetadata,
+					},
+					&cli.StringFlag{
+						Name:        "shard-dir",
+						Usage:       `Output the index as one file per RP, together with a manifest file, to this dir. The dir can be used as the index by other subcommands`,
+						Destination: &flagShardDir,
+					},
 				},
 				Action: func(c *cli.Context) error {
 					if c.NArg() == 0 {
@@ -79,19 +153,37 @@ func main() {
 						return fmt.Errorf("More than one arguments specified")
 					}
 					specdir := c.Args().First()
-					index, err := azidx.BuildIndex(specdir, flagDedup, flagServices.Value())
+					if flagShardDir != "" && flagOutput != "" {
+						return fmt.Errorf(`"-output" and "-shard-dir" are mutually exclusive`)
+					}
+					ctx, stop := signal.NotifyContext(c.Context, os.Interrupt)
+					defer stop()
+					index, err := azidx.BuildIndexWithOptions(ctx, azidx.BuildOptions{
+						SpecDir:           specdir,
+						DedupFile:         flagDedup,
+						Services:          flagServices.Value(),
+						Workers:           flagWorkers,
+						Progress:          buildProgressPrinter(os.Stderr),
+						OperationMetadata: flagOperationMetadata,
+					})
 					if err != nil {
 						return err
 					}
-					b, err := json.MarshalIndent(index, "", "  ")
-					if err != nil {
-						log.Fatal(err)
+					if flagShardDir != "" {
+						return azidx.WriteShards(flagShardDir, index, azidx.IndexEncoding(flagEncoding))
+					}
+					var buf bytes.Buffer
+					if err := azidx.EncodeIndex(&buf, index, azidx.IndexEncoding(flagEncoding)); err != nil {
+						return err
 					}
 					if flagOutput == "" {
-						fmt.Println(string(b))
-						return nil
+						if flagEncoding == string(azidx.IndexEncodingJSON) {
+							buf.WriteString("\n")
+						}
+						_, err := os.Stdout.Write(buf.Bytes())
+						return err
 					}
-					return os.WriteFile(flagOutput, b, 0644)
+					return os.WriteFile(flagOutput, buf.Bytes(), 0644)
 				},
 			},
 			{
@@ -113,57 +205,163 @@ func main() {
 						Name:        "method",
 						Usage:       `The request method (e.g. GET)`,
 						Destination: &flagMethod,
-						Required:    true,
 					},
 					&cli.StringFlag{
 						Name:        "url",
 						Usage:       `The request URL`,
 						Destination: &flagURL,
-						Required:    true,
 					},
 					&cli.StringFlag{
 						Name:        "specdir",
-						Usage:       `The spec dir, which is used to generate the Github permlink to the operation (the commit of the repo has to be the same as the index)`,
+						Usage:       `The spec dir, which is used to generate the Github permlink to the operation, or to resolve the operationId in batch mode for the index that has no operation metadata (the commit of the repo has to be the same as the index)`,
 						Destination: &flagSpecDir,
 					},
+					&cli.StringFlag{
+						Name:        "input",
+						Usage:       `The file of recorded requests to lookup in batch, used instead of "-method" and "-url"`,
+						Destination: &flagInput,
+					},
+					&cli.StringFlag{
+						Name:        "input-format",
+						Usage:       `The format of the input file (jsonl | har | terraform | azcli). Defaults to "har" for the *.har file, otherwise, "jsonl"`,
+						Destination: &flagInputFormat,
+					},
+					&cli.StringSliceFlag{
+						Name:        "hosts",
+						Usage:       `The ARM hosts, the recorded requests to other hosts are ignored`,
+						Destination: &flagHosts,
+						Value:       cli.NewStringSlice(record.DefaultARMHosts...),
+					},
+					&cli.StringFlag{
+						Name:        "output",
+						Aliases:     []string{"o"},
+						Usage:       `The output file of the batch lookup`,
+						Destination: &flagOutput,
+					},
+					&cli.StringFlag{
+						Name:        "output-format",
+						Usage:       `The output format of the batch lookup (jsonl | har). The "har" format is only available for the HAR input, which outputs the annotated HAR`,
+						Destination: &flagOutputFormat,
+						Value:       "jsonl",
+					},
+					&cli.BoolFlag{
+						Name:        "examples",
+						Usage:       `List the x-ms-examples files of the matched operation, ranked by how closely their request parameters match the URL (requires "-specdir")`,
+						Destination: &flagListExamples,
+					},
 				},
 				Action: func(c *cli.Context) error {
-					b, err := os.ReadFile(flagIndex)
-					if err != nil {
-						return fmt.Errorf("reading index file %s: %v", flagIndex, err)
+					if flagListExamples && flagSpecDir == "" {
+						return fmt.Errorf(`"-examples" requires "-specdir"`)
+					}
+					if flagInput != "" {
+						index, err := loadIndex(flagIndex)
+						if err != nil {
+							return err
+						}
+						return batchLookup(index)
 					}
-					var index azidx.Index
-					if err := json.Unmarshal(b, &index); err != nil {
-						return fmt.Errorf("unmarshal index file: %v", err)
+					if flagMethod == "" || flagURL == "" {
+						return fmt.Errorf(`either "-input", or "-method" and "-url" has to be specified`)
 					}
 					uRL, err := url.Parse(flagURL)
 					if err != nil {
 						return fmt.Errorf("parsing URL %s: %v", flagURL, err)
 					}
-					ref, err := index.Lookup(flagMethod, *uRL)
-					if err != nil {
-						return err
+					// Only the needed shards are loaded for the shard dir
+					var (
+						result    *azidx.LookupResult
+						commit    string
+						lookupErr error
+					)
+					if fi, err := os.Stat(flagIndex); err == nil && fi.IsDir() {
+						s, err := azidx.OpenShardedIndex(flagIndex)
+						if err != nil {
+							return fmt.Errorf("opening shard dir %s: %v", flagIndex, err)
+						}
+						commit = s.Manifest().Commit
+						result, lookupErr = s.LookupOperation(flagMethod, *uRL)
+					} else {
+						index, err := loadIndex(flagIndex)
+						if err != nil {
+							return err
+						}
+						commit = index.Commit
+						result, lookupErr = index.LookupOperation(flagMethod, *uRL)
 					}
+					if lookupErr != nil {
+						// The polling request that has no explicit definition is not a failure
+						var lerr *azidx.LookupError
+						if errors.As(lookupErr, &lerr) && lerr.LROPoll != nil {
+							fmt.Print(lroPollOutput(lerr.LROPoll))
+							return nil
+						}
+						return lookupErr
+					}
+					ref := &result.Ref
 
 					out := fmt.Sprintf(`
 Ref     : %s
 `, ref.String())
+					if result.Names != nil {
+						out += "Type    : " + result.Names.ResourceType() + "\n"
+					}
+					if poll := result.LROPoll; poll != nil {
+						out += "Poll    : " + poll.Kind + " of " + poll.RP + "\n"
+					}
+					if meta := result.Metadata; meta != nil {
+						out += "Path    : " + meta.Path + "\n"
+						out += "OpID    : " + meta.OperationID + "\n"
+						var traits []string
+						if meta.LongRunning {
+							trait := "long running"
+							if meta.FinalStateVia != "" {
+								trait += " (final-state-via: " + meta.FinalStateVia + ")"
+							}
+							traits = append(traits, trait)
+						}
+						if meta.Pageable {
+							traits = append(traits, "pageable")
+						}
+						if meta.Deprecated {
+							traits = append(traits, "deprecated")
+						}
+						if len(traits) != 0 {
+							out += "Traits  : " + strings.Join(traits, ", ") + "\n"
+						}
+					}
+
+					if flagListExamples {
+						op, err := azidx.ResolveOperation(flagSpecDir, *ref)
+						if err != nil {
+							return fmt.Errorf("resolving %s: %v", ref.String(), err)
+						}
+						files, err := azidx.RankExamples(flagSpecDir, op, *uRL)
+						if err != nil {
+							return err
+						}
+						out += "Examples:\n"
+						for _, file := range files {
+							out += fmt.Sprintf("  [%d] %s: %s\n", file.Score, file.Title, file.Path)
+						}
+					}
 
 					if flagSpecDir != "" {
 						flagSpecDir, err = filepath.Abs(flagSpecDir)
 						if err != nil {
 							return err
 						}
-						ref.GetURL().Path = filepath.Join(flagSpecDir, ref.GetURL().Path)
-						pos, err := getPosition(ref)
+						// The ref is shared by the index, so its URL is not changed in place
+						specFile := filepath.Join(flagSpecDir, ref.GetURL().Path)
+						pos, err := getPosition(specFile, ref)
 						if err != nil {
 							return err
 						}
-						link, err := azidx.BuildGithubLink(ref.GetURL().Path, *pos, index.Commit, flagSpecDir)
+						link, err := azidx.BuildGithubLink(specFile, *pos, commit, flagSpecDir)
 						if err != nil {
 							return err
 						}
-						out += "VSCode  : " + "vscode://file/" + ref.GetURL().Path + ":" + strconv.Itoa(pos.Line) + "\n"
+						out += "VSCode  : " + "vscode://file/" + specFile + ":" + strconv.Itoa(pos.Line) + "\n"
 						out += "Link    : " + link + "\n"
 					}
 
@@ -171,41 +369,1286 @@ Ref     : %s
 					return nil
 				},
 			},
-		},
-	}
-	if err := app.Run(os.Args); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
-	}
-}
-
-func initLogger() {
-	lvl := "INFO"
-	if flagVerbose {
-		lvl = "DEBUG"
-	}
-	logger := hclog.New(&hclog.LoggerOptions{
-		Name:  "azure-rest-api-index",
-		Level: hclog.LevelFromString(lvl),
-		Color: hclog.AutoColor,
-	})
-	azidx.SetLogger(logger)
-}
-
-func getPosition(ref *jsonreference.Ref) (*jsonpointerpos.JSONPointerPosition, error) {
-	b, err := os.ReadFile(ref.GetURL().Path)
-	if err != nil {
-		return nil, err
-	}
-
-	m, err := jsonpointerpos.GetPositions(string(b), []jsonpointer.Pointer{*ref.GetPointer()})
-	if err != nil {
-		return nil, err
-	}
+			{
+				Name:      "resolve",
+				Usage:     `Resolve the operation definition of a ref`,
+				UsageText: "azure-rest-api-index resolve [option]",
+				Before: func(ctx *cli.Context) error {
+					initLogger()
+					return nil
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "ref",
+						Usage:       `The operation ref (e.g. the output of the "lookup" subcommand)`,
+						Destination: &flagRef,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "specdir",
+						Usage:       `The spec dir (the commit of the repo has to be the same as the index)`,
+						Destination: &flagSpecDir,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "format",
+						Usage:       `The output format (text | json)`,
+						Destination: &flagFormat,
+						Value:       "text",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					refStr, err := url.PathUnescape(flagRef)
+					if err != nil {
+						return fmt.Errorf("unescaping ref %s: %v", flagRef, err)
+					}
+					ref, err := jsonreference.New(refStr)
+					if err != nil {
+						return fmt.Errorf("parsing ref %s: %v", flagRef, err)
+					}
+					op, err := azidx.ResolveOperation(flagSpecDir, ref)
+					if err != nil {
+						return err
+					}
+					switch flagFormat {
+					case "json":
+						b, err := json.MarshalIndent(op, "", "  ")
+						if err != nil {
+							return err
+						}
+						fmt.Println(string(b))
+					case "text":
+						fmt.Println(operationSummary(op))
+					default:
+						return fmt.Errorf("unknown format %q", flagFormat)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "validate",
+				Usage:     `Validate recorded requests (and responses) against their swagger definitions`,
+				UsageText: "azure-rest-api-index validate [option]",
+				Before: func(ctx *cli.Context) error {
+					initLogger()
+					return nil
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "index",
+						Usage:       `Use the pre-built index file by the "build" subcommand`,
+						Destination: &flagIndex,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "specdir",
+						Usage:       `The spec dir (the commit of the repo has to be the same as the index)`,
+						Destination: &flagSpecDir,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "method",
+						Usage:       `The request method (e.g. GET)`,
+						Destination: &flagMethod,
+					},
+					&cli.StringFlag{
+						Name:        "url",
+						Usage:       `The request URL`,
+						Destination: &flagURL,
+					},
+					&cli.StringFlag{
+						Name:        "body",
+						Usage:       `The file containing the JSON request body ("-" for stdin)`,
+						Destination: &flagBody,
+					},
+					&cli.IntFlag{
+						Name:        "status",
+						Usage:       `The response status code, which enables the response validation`,
+						Destination: &flagStatus,
+					},
+					&cli.StringFlag{
+						Name:        "response-body",
+						Usage:       `The file containing the JSON response body ("-" for stdin)`,
+						Destination: &flagResponseBody,
+					},
+					&cli.StringFlag{
+						Name:        "input",
+						Usage:       `The file of recorded requests (and responses), used instead of "-method", "-url", "-body", "-status" and "-response-body"`,
+						Destination: &flagInput,
+					},
+					&cli.StringFlag{
+						Name:        "input-format",
+						Usage:       `The format of the input file (jsonl | har | terraform | azcli). Defaults to "har" for the *.har file, otherwise, "jsonl"`,
+						Destination: &flagInputFormat,
+					},
+					&cli.StringSliceFlag{
+						Name:        "hosts",
+						Usage:       `The ARM hosts, the recorded requests to other hosts are ignored`,
+						Destination: &flagHosts,
+						Value:       cli.NewStringSlice(record.DefaultARMHosts...),
+					},
+					&cli.StringFlag{
+						Name:        "format",
+						Usage:       `The output format (text | json)`,
+						Destination: &flagFormat,
+						Value:       "text",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					index, err := loadIndex(flagIndex)
+					if err != nil {
+						return err
+					}
+					records, err := inputRecords()
+					if err != nil {
+						return err
+					}
+					validator := azidx.NewValidator(index, flagSpecDir)
+					var nViolation, nError int
+					for _, rec := range records {
+						uRL, err := url.Parse(rec.URL)
+						if err != nil {
+							return fmt.Errorf("parsing URL %s: %v", rec.URL, err)
+						}
+						result, err := validator.ValidateRequest(rec.Method, *uRL, rec.RequestBody)
+						nViolation += printValidationResult(rec, "request", result, err)
+						if err != nil {
+							nError++
+						}
+						if rec.StatusCode != 0 {
+							result, err := validator.ValidateResponse(rec.Method, *uRL, rec.StatusCode, rec.ResponseBody)
+							nViolation += printValidationResult(rec, "response", result, err)
+							if err != nil {
+								nError++
+							}
+						}
+					}
+					// The lookup or resolving error fails the validation as well, as the body is not validated at all
+					if nViolation != 0 || nError != 0 {
+						return fmt.Errorf("%d violation(s) and %d error(s) found", nViolation, nError)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "coverage",
+				Usage:     `Report the coverage of the indexed operations by the recorded requests`,
+				UsageText: "azure-rest-api-index coverage [option]",
+				Before: func(ctx *cli.Context) error {
+					initLogger()
+					return nil
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "index",
+						Usage:       `Use the pre-built index file by the "build" subcommand`,
+						Destination: &flagIndex,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "input",
+						Usage:       `The file of recorded requests`,
+						Destination: &flagInput,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "input-format",
+						Usage:       `The format of the input file (jsonl | har | terraform | azcli). Defaults to "har" for the *.har file, otherwise, "jsonl"`,
+						Destination: &flagInputFormat,
+					},
+					&cli.StringSliceFlag{
+						Name:        "hosts",
+						Usage:       `The ARM hosts, the recorded requests to other hosts are ignored`,
+						Destination: &flagHosts,
+						Value:       cli.NewStringSlice(record.DefaultARMHosts...),
+					},
+					&cli.BoolFlag{
+						Name:        "exercised-versions-only",
+						Usage:       `Only count the operations of the API versions that are hit at least once`,
+						Destination: &flagExercisedVersionsOnly,
+					},
+					&cli.StringFlag{
+						Name:        "format",
+						Usage:       `The output format (markdown | html | json)`,
+						Destination: &flagFormat,
+						Value:       "markdown",
+					},
+					&cli.StringFlag{
+						Name:        "output",
+						Aliases:     []string{"o"},
+						Usage:       `Output file`,
+						Destination: &flagOutput,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					index, err := loadIndex(flagIndex)
+					if err != nil {
+						return err
+					}
+					records, err := inputRecords()
+					if err != nil {
+						return err
+					}
+					cov := azidx.NewCoverage(index, azidx.CoverageOptions{
+						ExercisedVersionsOnly: flagExercisedVersionsOnly,
+					})
+					for _, rec := range records {
+						uRL, err := url.Parse(rec.URL)
+						if err != nil {
+							return fmt.Errorf("parsing URL %s: %v", rec.URL, err)
+						}
+						// The requests that match nothing are counted in the report
+						cov.Record(rec.Method, *uRL)
+					}
+					report := cov.Report()
 
-	pos, ok := m[ref.GetPointer().String()]
-	if !ok {
-		return nil, fmt.Errorf("can't find the pointer's position: %v", ref.String())
-	}
-	return &pos, nil
+					var out string
+					switch flagFormat {
+					case "markdown":
+						out = report.Markdown()
+					case "html":
+						out, err = report.HTML()
+						if err != nil {
+							return err
+						}
+					case "json":
+						b, err := json.MarshalIndent(report, "", "  ")
+						if err != nil {
+							return err
+						}
+						out = string(b)
+					default:
+						return fmt.Errorf("unknown format %q", flagFormat)
+					}
+					if flagOutput == "" {
+						fmt.Println(out)
+						return nil
+					}
+					return os.WriteFile(flagOutput, []byte(out), 0644)
+				},
+			},
+			{
+				Name:      "unmatched",
+				Usage:     `Report the recorded requests that match nothing in the index, grouped by the likely cause`,
+				UsageText: "azure-rest-api-index unmatched [option]",
+				Before: func(ctx *cli.Context) error {
+					initLogger()
+					return nil
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "index",
+						Usage:       `Use the pre-built index file by the "build" subcommand`,
+						Destination: &flagIndex,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "input",
+						Usage:       `The file of recorded requests`,
+						Destination: &flagInput,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "input-format",
+						Usage:       `The format of the input file (jsonl | har | terraform | azcli). Defaults to "har" for the *.har file, otherwise, "jsonl"`,
+						Destination: &flagInputFormat,
+					},
+					&cli.StringSliceFlag{
+						Name:        "hosts",
+						Usage:       `The ARM hosts, the recorded requests to other hosts are ignored`,
+						Destination: &flagHosts,
+						Value:       cli.NewStringSlice(record.DefaultARMHosts...),
+					},
+					&cli.IntFlag{
+						Name:        "examples",
+						Usage:       `The max count of example requests shown for each cause`,
+						Destination: &flagExamples,
+						Value:       azidx.DefaultUnmatchedExamples,
+					},
+					&cli.StringFlag{
+						Name:        "format",
+						Usage:       `The output format (text | json)`,
+						Destination: &flagFormat,
+						Value:       "text",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					index, err := loadIndex(flagIndex)
+					if err != nil {
+						return err
+					}
+					records, err := inputRecords()
+					if err != nil {
+						return err
+					}
+					report := azidx.NewUnmatchedReport(index, flagExamples)
+					for _, rec := range records {
+						uRL, err := url.Parse(rec.URL)
+						if err != nil {
+							return fmt.Errorf("parsing URL %s: %v", rec.URL, err)
+						}
+						report.Record(rec.Method, *uRL)
+					}
+					sum := report.Summary()
+					switch flagFormat {
+					case "text":
+						fmt.Print(sum.String())
+					case "json":
+						b, err := json.MarshalIndent(sum, "", "  ")
+						if err != nil {
+							return err
+						}
+						fmt.Println(string(b))
+					default:
+						return fmt.Errorf("unknown format %q", flagFormat)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "stats",
+				Usage:     `Print the statistics of an index`,
+				UsageText: "azure-rest-api-index stats [option]",
+				Before: func(ctx *cli.Context) error {
+					initLogger()
+					return nil
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "index",
+						Usage:       `Use the pre-built index file by the "build" subcommand`,
+						Destination: &flagIndex,
+						Required:    true,
+					},
+					&cli.IntFlag{
+						Name:        "top",
+						Usage:       `The count of the largest RPs to print (for the text format only). Non-positive value prints every RP`,
+						Destination: &flagTop,
+						Value:       10,
+					},
+					&cli.StringFlag{
+						Name:        "format",
+						Usage:       `The output format (text | json)`,
+						Destination: &flagFormat,
+						Value:       "text",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					index, err := loadIndex(flagIndex)
+					if err != nil {
+						return err
+					}
+					stats := index.Stats()
+					switch flagFormat {
+					case "text":
+						fmt.Print(stats.Text(flagTop))
+					case "json":
+						b, err := json.MarshalIndent(stats, "", "  ")
+						if err != nil {
+							return err
+						}
+						fmt.Println(string(b))
+					default:
+						return fmt.Errorf("unknown format %q", flagFormat)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "versions",
+				Usage:     `List the API versions that support an operation, and pick the latest one`,
+				UsageText: "azure-rest-api-index versions [option]",
+				Before: func(ctx *cli.Context) error {
+					initLogger()
+					return nil
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "index",
+						Usage:       `Use the pre-built index file by the "build" subcommand`,
+						Destination: &flagIndex,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "rp",
+						Usage:       `The resource provider, e.g. Microsoft.Network`,
+						Destination: &flagRP,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "rt",
+						Usage:       `The resource type, e.g. virtualNetworks/subnets. Empty means the RP itself`,
+						Destination: &flagRT,
+					},
+					&cli.StringFlag{
+						Name:        "method",
+						Usage:       `The HTTP method`,
+						Destination: &flagMethod,
+						Value:       "GET",
+					},
+					&cli.StringFlag{
+						Name:        "action",
+						Usage:       `The action or collection type, e.g. listKeys`,
+						Destination: &flagAction,
+					},
+					&cli.StringFlag{
+						Name:        "policy",
+						Usage:       `The policy to pick the latest API version (stable | preview | any)`,
+						Destination: &flagPolicy,
+						Value:       string(azidx.VersionPolicyStable),
+					},
+					&cli.StringFlag{
+						Name:        "format",
+						Usage:       `The output format (text | json)`,
+						Destination: &flagFormat,
+						Value:       "text",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					index, err := loadIndex(flagIndex)
+					if err != nil {
+						return err
+					}
+					versions := index.SupportedAPIVersions(azidx.VersionQuery{
+						RP:     flagRP,
+						RT:     flagRT,
+						Method: azidx.OperationKind(flagMethod),
+						Action: flagAction,
+					})
+					latest, err := azidx.LatestAPIVersion(versions, azidx.VersionPolicy(flagPolicy))
+					if err != nil {
+						return err
+					}
+					switch flagFormat {
+					case "text":
+						for _, v := range versions {
+							if v.Preview {
+								fmt.Printf("%s (preview)\n", v.Version)
+								continue
+							}
+							fmt.Println(v.Version)
+						}
+						if latest == nil {
+							fmt.Printf("latest (%s): none\n", flagPolicy)
+							return nil
+						}
+						fmt.Printf("latest (%s): %s\n", flagPolicy, latest.Version)
+					case "json":
+						out := struct {
+							Versions []azidx.APIVersionSupport `json:"versions"`
+							Latest   *azidx.APIVersionSupport  `json:"latest"`
+						}{
+							Versions: versions,
+							Latest:   latest,
+						}
+						b, err := json.MarshalIndent(out, "", "  ")
+						if err != nil {
+							return err
+						}
+						fmt.Println(string(b))
+					default:
+						return fmt.Errorf("unknown format %q", flagFormat)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "tree",
+				Usage:     `Print the resource type hierarchy of the RPs`,
+				UsageText: "azure-rest-api-index tree [option]",
+				Before: func(ctx *cli.Context) error {
+					initLogger()
+					return nil
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "index",
+						Usage:       `Use the pre-built index file by the "build" subcommand`,
+						Destination: &flagIndex,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "rp",
+						Usage:       `The resource provider, e.g. Microsoft.Network. Empty means every RP (excluding the wildcard RP)`,
+						Destination: &flagRP,
+					},
+					&cli.StringFlag{
+						Name:        "format",
+						Usage:       `The output format (text | json | dot)`,
+						Destination: &flagFormat,
+						Value:       "text",
+					},
+					&cli.StringFlag{
+						Name:        "output",
+						Aliases:     []string{"o"},
+						Usage:       `Output file`,
+						Destination: &flagOutput,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					index, err := loadIndex(flagIndex)
+					if err != nil {
+						return err
+					}
+					rps := []string{flagRP}
+					if flagRP == "" {
+						rps = nil
+						for rp := range index.ResourceProviders {
+							if rp == azidx.Wildcard {
+								continue
+							}
+							rps = append(rps, rp)
+						}
+						sort.Strings(rps)
+					}
+					var trees []*azidx.RTNode
+					for _, rp := range rps {
+						tree := index.ResourceTypeTree(rp)
+						if tree == nil {
+							return fmt.Errorf("rp %s not found in the index", rp)
+						}
+						trees = append(trees, tree)
+					}
+
+					var out string
+					switch flagFormat {
+					case "text":
+						for _, tree := range trees {
+							out += tree.Text()
+						}
+					case "json":
+						b, err := json.MarshalIndent(trees, "", "  ")
+						if err != nil {
+							return err
+						}
+						out = string(b) + "\n"
+					case "dot":
+						out = azidx.DOT(trees)
+					default:
+						return fmt.Errorf("unknown format %q", flagFormat)
+					}
+					if flagOutput == "" {
+						fmt.Print(out)
+						return nil
+					}
+					return os.WriteFile(flagOutput, []byte(out), 0644)
+				},
+			},
+			{
+				Name:      "migrate",
+				Usage:     `Migrate an index file to the current format version`,
+				UsageText: "azure-rest-api-index migrate [option]",
+				Before: func(ctx *cli.Context) error {
+					initLogger()
+					return nil
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "index",
+						Usage:       `The index file (of any encoding), or the shard dir, to migrate`,
+						Destination: &flagIndex,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "specdir",
+						Usage:       `The spec dir, which is used to fill in the information absent in the older format versions, e.g. the operation metadata (the commit of the repo has to be the same as the index)`,
+						Destination: &flagSpecDir,
+					},
+					&cli.StringFlag{
+						Name:        "encoding",
+						Usage:       `The encoding of the output (json | gzip | binary). Defaults to the encoding of the index`,
+						Destination: &flagEncoding,
+					},
+					&cli.StringFlag{
+						Name:        "output",
+						Aliases:     []string{"o"},
+						Usage:       `Output file, or the output dir for the shard dir`,
+						Destination: &flagOutput,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					// The index is read without being migrated, so that the specdir can be used to migrate it
+					var (
+						index    *azidx.Index
+						encoding azidx.IndexEncoding
+						isShards bool
+					)
+					if fi, err := os.Stat(flagIndex); err == nil && fi.IsDir() {
+						if flagOutput == "" {
+							return fmt.Errorf(`"-output" dir is required for migrating the shard dir`)
+						}
+						isShards = true
+						if index, encoding, err = azidx.ReadShardsAsIs(flagIndex); err != nil {
+							return fmt.Errorf("reading shard dir %s: %v", flagIndex, err)
+						}
+					} else {
+						b, err := os.ReadFile(flagIndex)
+						if err != nil {
+							return fmt.Errorf("reading index file %s: %v", flagIndex, err)
+						}
+						if index, encoding, err = azidx.DecodeIndexAsIs(b); err != nil {
+							return fmt.Errorf("decoding index file %s: %v", flagIndex, err)
+						}
+					}
+					if err := azidx.MigrateIndex(index, flagSpecDir); err != nil {
+						return err
+					}
+					if flagEncoding != "" {
+						encoding = azidx.IndexEncoding(flagEncoding)
+					}
+					if isShards {
+						return azidx.WriteShards(flagOutput, index, encoding)
+					}
+					var buf bytes.Buffer
+					if err := azidx.EncodeIndex(&buf, index, encoding); err != nil {
+						return err
+					}
+					if flagOutput == "" {
+						if encoding == azidx.IndexEncodingJSON {
+							buf.WriteString("\n")
+						}
+						_, err := os.Stdout.Write(buf.Bytes())
+						return err
+					}
+					return os.WriteFile(flagOutput, buf.Bytes(), 0644)
+				},
+			},
+			{
+				Name:      "export",
+				Usage:     `Export the index to a format that is easier to query`,
+				UsageText: "azure-rest-api-index export [option]",
+				Before: func(ctx *cli.Context) error {
+					initLogger()
+					return nil
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "index",
+						Usage:       `Use the pre-built index file by the "build" subcommand`,
+						Destination: &flagIndex,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "format",
+						Usage:       `The export format (sqlite | csv | tsv)`,
+						Destination: &flagFormat,
+						Value:       "sqlite",
+					},
+					&cli.StringFlag{
+						Name:        "output",
+						Aliases:     []string{"o"},
+						Usage:       `Output file. It is required for the "sqlite" format, otherwise, defaults to stdout`,
+						Destination: &flagOutput,
+					},
+					&cli.StringSliceFlag{
+						Name:        "columns",
+						Usage:       fmt.Sprintf(`The columns of the csv/tsv export (%s)`, strings.Join(export.AllColumns, " | ")),
+						Destination: &flagColumns,
+						Value:       cli.NewStringSlice(export.DefaultColumns...),
+					},
+					&cli.StringSliceFlag{
+						Name:        "rp",
+						Usage:       `Only export the operations of these RPs (e.g. Microsoft.Network), for the csv/tsv export`,
+						Destination: &flagRPs,
+					},
+					&cli.StringSliceFlag{
+						Name:        "api-version",
+						Usage:       `Only export the operations of these API versions, for the csv/tsv export`,
+						Destination: &flagAPIVersions,
+					},
+					&cli.StringSliceFlag{
+						Name:        "method",
+						Usage:       `Only export the operations of these methods (e.g. PUT), for the csv/tsv export`,
+						Destination: &flagMethods,
+					},
+					&cli.StringSliceFlag{
+						Name:        "rt",
+						Usage:       `Only export the operations of these resource types (e.g. virtualNetworks/subnets), for the csv/tsv export`,
+						Destination: &flagRTs,
+					},
+					&cli.StringSliceFlag{
+						Name:        "action",
+						Usage:       `Only export the operations of these actions (e.g. listKeys), for the csv/tsv export`,
+						Destination: &flagActions,
+					},
+					&cli.StringFlag{
+						Name:        "stability",
+						Usage:       `Only export the operations of the stable or preview API versions (stable | preview | any), for the csv/tsv export`,
+						Destination: &flagStability,
+						Value:       string(azidx.VersionPolicyAny),
+					},
+				},
+				Action: func(c *cli.Context) error {
+					index, err := loadIndex(flagIndex)
+					if err != nil {
+						return err
+					}
+					var comma rune
+					switch flagFormat {
+					case "sqlite":
+						if flagOutput == "" {
+							return fmt.Errorf(`"-output" is required for the "sqlite" format`)
+						}
+						return export.WriteSQLite(flagOutput, index)
+					case "csv":
+						comma = ','
+					case "tsv":
+						comma = '\t'
+					default:
+						return fmt.Errorf("unknown format %q", flagFormat)
+					}
+					switch stability := azidx.VersionPolicy(flagStability); stability {
+					case azidx.VersionPolicyStable, azidx.VersionPolicyPreview, azidx.VersionPolicyAny:
+					default:
+						return fmt.Errorf("unknown stability %q", flagStability)
+					}
+					rows := export.FilterRows(export.OperationRows(index), export.Filter{
+						RPs:         flagRPs.Value(),
+						APIVersions: flagAPIVersions.Value(),
+						Methods:     flagMethods.Value(),
+						RTs:         flagRTs.Value(),
+						ACTs:        flagActions.Value(),
+						Stability:   azidx.VersionPolicy(flagStability),
+					})
+					var buf bytes.Buffer
+					if err := export.WriteTable(&buf, rows, flagColumns.Value(), comma); err != nil {
+						return err
+					}
+					if flagOutput == "" {
+						_, err := os.Stdout.Write(buf.Bytes())
+						return err
+					}
+					return os.WriteFile(flagOutput, buf.Bytes(), 0644)
+				},
+			},
+			{
+				Name:      "proxy",
+				Usage:     `Run a reverse proxy that forwards the requests to the upstream, and records each request annotated with its swagger operation`,
+				UsageText: "azure-rest-api-index proxy [option]",
+				Before: func(ctx *cli.Context) error {
+					initLogger()
+					return nil
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "index",
+						Usage:       `Use the pre-built index file by the "build" subcommand`,
+						Destination: &flagIndex,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "upstream",
+						Usage:       `The upstream URL that the requests are forwarded to`,
+						Destination: &flagUpstream,
+						Value:       proxy.DefaultUpstream,
+					},
+					&cli.StringFlag{
+						Name:        "listen",
+						Usage:       `The address to listen on`,
+						Destination: &flagListen,
+						Value:       "127.0.0.1:8080",
+					},
+					&cli.StringFlag{
+						Name:        "tls-cert",
+						Usage:       `The TLS certificate file, to serve HTTPS (together with "-tls-key")`,
+						Destination: &flagTLSCert,
+					},
+					&cli.StringFlag{
+						Name:        "tls-key",
+						Usage:       `The TLS private key file, to serve HTTPS (together with "-tls-cert")`,
+						Destination: &flagTLSKey,
+					},
+					&cli.StringFlag{
+						Name:        "specdir",
+						Usage:       `The spec dir, which is used to validate the request and response bodies (the commit of the repo has to be the same as the index)`,
+						Destination: &flagSpecDir,
+					},
+					&cli.StringFlag{
+						Name:        "output",
+						Aliases:     []string{"o"},
+						Usage:       `The JSONL file that the records are appended to. Defaults to stdout`,
+						Destination: &flagOutput,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if (flagTLSCert == "") != (flagTLSKey == "") {
+						return fmt.Errorf(`"-tls-cert" and "-tls-key" must be specified together`)
+					}
+					index, err := loadIndex(flagIndex)
+					if err != nil {
+						return err
+					}
+					out := os.Stdout
+					if flagOutput != "" {
+						out, err = os.OpenFile(flagOutput, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+						if err != nil {
+							return fmt.Errorf("opening output file %s: %v", flagOutput, err)
+						}
+						defer out.Close()
+					}
+					p, err := proxy.New(index, out, proxy.Options{
+						Upstream: flagUpstream,
+						SpecDir:  flagSpecDir,
+					})
+					if err != nil {
+						return err
+					}
+
+					fmt.Fprintf(os.Stderr, "Proxying %s to %s\n", flagListen, flagUpstream)
+					return serve(c.Context, &http.Server{Addr: flagListen, Handler: p})
+				},
+			},
+			{
+				Name:      "mock",
+				Usage:     `Run a mock ARM server that answers each request by the x-ms-examples of the matched operation, or by a minimal body generated from the response schema`,
+				UsageText: "azure-rest-api-index mock [option]",
+				Before: func(ctx *cli.Context) error {
+					initLogger()
+					return nil
+				},
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:        "index",
+						Usage:       `Use the pre-built index file by the "build" subcommand`,
+						Destination: &flagIndex,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "specdir",
+						Usage:       `The spec dir (the commit of the repo has to be the same as the index)`,
+						Destination: &flagSpecDir,
+						Required:    true,
+					},
+					&cli.StringFlag{
+						Name:        "listen",
+						Usage:       `The address to listen on`,
+						Destination: &flagListen,
+						Value:       "127.0.0.1:8080",
+					},
+					&cli.StringFlag{
+						Name:        "tls-cert",
+						Usage:       `The TLS certificate file, to serve HTTPS (together with "-tls-key")`,
+						Destination: &flagTLSCert,
+					},
+					&cli.StringFlag{
+						Name:        "tls-key",
+						Usage:       `The TLS private key file, to serve HTTPS (together with "-tls-cert")`,
+						Destination: &flagTLSKey,
+					},
+				},
+				Action: func(c *cli.Context) error {
+					if (flagTLSCert == "") != (flagTLSKey == "") {
+						return fmt.Errorf(`"-tls-cert" and "-tls-key" must be specified together`)
+					}
+					index, err := loadIndex(flagIndex)
+					if err != nil {
+						return err
+					}
+					fmt.Fprintf(os.Stderr, "Serving mock ARM on %s\n", flagListen)
+					return serve(c.Context, &http.Server{Addr: flagListen, Handler: mock.New(index, flagSpecDir)})
+				},
+			},
+		},
+	}
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func initLogger() {
+	lvl := "INFO"
+	if flagVerbose {
+		lvl = "DEBUG"
+	}
+	logger := hclog.New(&hclog.LoggerOptions{
+		Name:  "azure-rest-api-index",
+		Level: hclog.LevelFromString(lvl),
+		Color: hclog.AutoColor,
+	})
+	azidx.SetLogger(logger)
+}
+
+// serve runs the server until interrupted, which serves HTTPS if "-tls-cert" and "-tls-key" are specified.
+func serve(ctx context.Context, server *http.Server) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	var err error
+	if flagTLSCert != "" {
+		err = server.ListenAndServeTLS(flagTLSCert, flagTLSKey)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// buildProgressPrinter returns the progress callback that renders the build progress as a single line, which is overwritten on each update.
+// It returns nil if w is not a terminal, where the progress line would only pollute the output.
+func buildProgressPrinter(w *os.File) func(azidx.BuildProgress) {
+	if fi, err := w.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		return nil
+	}
+	return func(p azidx.BuildProgress) {
+		// Clear the line before rendering, as the spec path varies in length
+		fmt.Fprintf(w, "\r\033[KParsing specs: %d/%d %s", p.Parsed, p.Total, p.Spec)
+		if p.Parsed == p.Total {
+			fmt.Fprintln(w)
+		}
+	}
+}
+
+// loadIndex loads the index file, or all the shards if the path is a shard dir.
+func loadIndex(path string) (*azidx.Index, error) {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		s, err := azidx.OpenShardedIndex(path)
+		if err != nil {
+			return nil, fmt.Errorf("opening shard dir %s: %v", path, err)
+		}
+		return s.LoadAll()
+	}
+	return azidx.LoadIndexFile(path)
+}
+
+// inputRecords returns the records from the "-input" file, or the single record built from the "-method", "-url" and "-body".
+func inputRecords() ([]record.Record, error) {
+	if flagInput != "" {
+		b, err := os.ReadFile(flagInput)
+		if err != nil {
+			return nil, fmt.Errorf("reading input file %s: %v", flagInput, err)
+		}
+		records, err := readRecords(b)
+		if err != nil {
+			return nil, err
+		}
+		var out []record.Record
+		for _, rec := range records {
+			if isARMRecord(rec) {
+				out = append(out, rec)
+			}
+		}
+		return out, nil
+	}
+	if flagMethod == "" || flagURL == "" {
+		return nil, fmt.Errorf(`either "-input", or "-method" and "-url" has to be specified`)
+	}
+	rec := record.Record{
+		Method:     flagMethod,
+		URL:        flagURL,
+		StatusCode: flagStatus,
+	}
+	if flagBody == "-" && flagResponseBody == "-" {
+		return nil, fmt.Errorf(`"-body" and "-response-body" can't both be read from stdin`)
+	}
+	b, err := readBodyFile(flagBody)
+	if err != nil {
+		return nil, err
+	}
+	rec.RequestBody = b
+	b, err = readBodyFile(flagResponseBody)
+	if err != nil {
+		return nil, err
+	}
+	rec.ResponseBody = b
+	return []record.Record{rec}, nil
+}
+
+func inputFormat() string {
+	if flagInputFormat != "" {
+		return flagInputFormat
+	}
+	if strings.EqualFold(filepath.Ext(flagInput), ".har") {
+		return "har"
+	}
+	return "jsonl"
+}
+
+// readRecords reads the records from the content of the input file, in the format of the "-input-format".
+func readRecords(b []byte) ([]record.Record, error) {
+	switch format := inputFormat(); format {
+	case "jsonl":
+		return record.ReadJSONL(bytes.NewReader(b))
+	case "har":
+		return record.ReadHAR(b)
+	case "terraform":
+		return record.ReadTerraformLog(bytes.NewReader(b))
+	case "azcli":
+		return record.ReadAzCLILog(bytes.NewReader(b))
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+}
+
+// isARMRecord tells whether the record is targeting one of the "-hosts". The record whose URL has no host is regarded as an ARM request.
+func isARMRecord(rec record.Record) bool {
+	if uRL, err := url.Parse(rec.URL); err == nil && uRL.Host == "" {
+		return true
+	}
+	return record.HasHost(rec, flagHosts.Value())
+}
+
+// lroPollOutput renders the LRO polling request that has no explicit definition.
+func lroPollOutput(poll *azidx.LROPoll) string {
+	out := fmt.Sprintf(`
+Poll    : %s
+RP      : %s
+`, poll.Kind, poll.RP)
+	if poll.Location != "" {
+		out += "Location: " + poll.Location + "\n"
+	}
+	out += "ID      : " + poll.ID + "\n"
+	return out
+}
+
+type lookupAnnotation struct {
+	Method string `json:"method,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Ref    string `json:"ref,omitempty"`
+	// ResourceType and Path are in the original casing of the Swagger.
+	ResourceType string `json:"resource_type,omitempty"`
+	Path         string `json:"path,omitempty"`
+	OperationID  string `json:"operation_id,omitempty"`
+	LongRunning  bool   `json:"long_running,omitempty"`
+	Pageable     bool   `json:"pageable,omitempty"`
+	Deprecated   bool   `json:"deprecated,omitempty"`
+	Error        string `json:"error,omitempty"`
+	// Cause is the likely cause of the request matching nothing.
+	Cause string `json:"cause,omitempty"`
+	// Examples are the x-ms-examples files of the matched operation, ranked by how closely they match the request.
+	Examples []azidx.ExampleFile `json:"examples,omitempty"`
+	// LROPoll is set if the request is polling a long running operation, linked to the operation that produced it if possible.
+	LROPoll *azidx.LROPoll `json:"lro_poll,omitempty"`
+}
+
+// batchLookup looks up the records of the "-input" file, and outputs the annotations in JSONL, or the annotated HAR.
+func batchLookup(index *azidx.Index) error {
+	b, err := os.ReadFile(flagInput)
+	if err != nil {
+		return fmt.Errorf("reading input file %s: %v", flagInput, err)
+	}
+	records, err := readRecords(b)
+	if err != nil {
+		return err
+	}
+	if flagOutputFormat == "har" && inputFormat() != "har" {
+		return fmt.Errorf(`output format "har" is only available for the HAR input`)
+	}
+	var resolver *azidx.OperationResolver
+	if flagSpecDir != "" {
+		resolver = azidx.NewOperationResolver(flagSpecDir)
+	}
+
+	// The records are in order, so that the polling requests follow the long running operations that produced them
+	tracker := azidx.NewLROTracker()
+	annotations := map[int]interface{}{}
+	var out []byte
+	for i, rec := range records {
+		if !isARMRecord(rec) {
+			continue
+		}
+		annotation := lookupAnnotation{
+			Method: rec.Method,
+			URL:    rec.URL,
+		}
+		if uRL, err := url.Parse(rec.URL); err != nil {
+			annotation.Error = fmt.Sprintf("parsing URL %s: %v", rec.URL, err)
+		} else if result, err := index.LookupOperation(rec.Method, *uRL); err != nil {
+			var lerr *azidx.LookupError
+			if errors.As(err, &lerr) && lerr.LROPoll != nil {
+				// The polling request is not a failure
+				tracker.Link(lerr.LROPoll, *uRL)
+				annotation.LROPoll = lerr.LROPoll
+			} else {
+				annotation.Error = err.Error()
+				if lerr != nil {
+					annotation.Cause = string(lerr.Cause)
+				}
+			}
+		} else {
+			tracker.Observe(rec.Method, *uRL, result, rec.ResponseHeaders)
+			if result.LROPoll != nil {
+				tracker.Link(result.LROPoll, *uRL)
+				annotation.LROPoll = result.LROPoll
+			}
+			ref := &result.Ref
+			annotation.Ref = ref.String()
+			if result.Names != nil {
+				annotation.ResourceType = result.Names.ResourceType()
+			}
+			if meta := result.Metadata; meta != nil {
+				annotation.Path = meta.Path
+				annotation.OperationID = meta.OperationID
+				annotation.LongRunning = meta.LongRunning
+				annotation.Pageable = meta.Pageable
+				annotation.Deprecated = meta.Deprecated
+			}
+			// Resolve the operation for the index that has no operation metadata, or for listing the examples
+			if resolver != nil && (result.Metadata == nil || flagListExamples) {
+				op, err := resolver.Resolve(*ref)
+				if err != nil {
+					annotation.Error = fmt.Sprintf("resolving %s: %v", ref.String(), err)
+				} else {
+					annotation.OperationID = op.OperationID
+					if flagListExamples {
+						if annotation.Examples, err = azidx.RankExamples(flagSpecDir, op, *uRL); err != nil {
+							annotation.Error = err.Error()
+						}
+					}
+				}
+			}
+		}
+		switch flagOutputFormat {
+		case "jsonl":
+			b, err := json.Marshal(annotation)
+			if err != nil {
+				return err
+			}
+			out = append(out, append(b, '\n')...)
+		case "har":
+			// The method and URL are already recorded in the entry
+			annotation.Method, annotation.URL = "", ""
+			annotations[i] = annotation
+		default:
+			return fmt.Errorf("unknown output format %q", flagOutputFormat)
+		}
+	}
+	if flagOutputFormat == "har" {
+		out, err = record.AnnotateHAR(b, annotations)
+		if err != nil {
+			return err
+		}
+	}
+	if flagOutput == "" {
+		fmt.Print(string(out))
+		return nil
+	}
+	return os.WriteFile(flagOutput, out, 0644)
+}
+
+// readBodyFile reads the body from the file, or from the stdin if the path is "-".
+func readBodyFile(path string) ([]byte, error) {
+	switch path {
+	case "":
+		return nil, nil
+	case "-":
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("reading body from stdin: %v", err)
+		}
+		return b, nil
+	default:
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading body file %s: %v", path, err)
+		}
+		return b, nil
+	}
+}
+
+// printValidationResult prints the validation result of the request or response (as is indicated by the target) of a record, and returns the count of violations.
+func printValidationResult(rec record.Record, target string, result *azidx.ValidationResult, err error) int {
+	if flagFormat == "json" {
+		out := struct {
+			Method string `json:"method"`
+			URL    string `json:"url"`
+			Target string `json:"target"`
+			*azidx.ValidationResult
+			Error string `json:"error,omitempty"`
+		}{
+			Method:           rec.Method,
+			URL:              rec.URL,
+			Target:           target,
+			ValidationResult: result,
+		}
+		if err != nil {
+			out.Error = err.Error()
+		}
+		b, _ := json.Marshal(out)
+		fmt.Println(string(b))
+	} else {
+		fmt.Printf("%s %s (%s)\n", rec.Method, rec.URL, target)
+		if err != nil {
+			fmt.Printf("  Error: %v\n", err)
+		} else {
+			fmt.Printf("  Ref: %s\n", result.Ref)
+			for _, verr := range result.Errors {
+				fmt.Printf("  %s\n", verr)
+			}
+		}
+	}
+	if result == nil {
+		return 0
+	}
+	return len(result.Errors)
+}
+
+// getPosition returns the position of the ref's JSON pointer in the spec file.
+func getPosition(specFile string, ref *jsonreference.Ref) (*jsonpointerpos.JSONPointerPosition, error) {
+	b, err := os.ReadFile(specFile)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := jsonpointerpos.GetPositions(string(b), []jsonpointer.Pointer{*ref.GetPointer()})
+	if err != nil {
+		return nil, err
+	}
+
+	pos, ok := m[ref.GetPointer().String()]
+	if !ok {
+		return nil, fmt.Errorf("can't find the pointer's position: %v", ref.String())
+	}
+	return &pos, nil
+}
+
+func operationSummary(op *azidx.ResolvedOperation) string {
+	out := fmt.Sprintf(`
+Ref         : %s
+Operation   : %s %s
+OperationId : %s
+`, op.Ref, op.Method, op.Path, op.OperationID)
+	if op.Summary != "" {
+		out += "Summary     : " + op.Summary + "\n"
+	}
+	if op.Deprecated {
+		out += "Deprecated  : true\n"
+	}
+	if op.LongRunning {
+		out += "LRO         : true"
+		if v, ok := op.LongRunningOptions["final-state-via"]; ok {
+			out += fmt.Sprintf(" (final-state-via: %v)", v)
+		}
+		out += "\n"
+	}
+	if op.Pageable != nil {
+		out += "Pageable    : nextLinkName=" + op.Pageable.NextLinkName + "\n"
+	}
+	if len(op.Parameters) != 0 {
+		out += "Parameters  :\n"
+		for _, param := range op.Parameters {
+			typ := param.Type
+			if param.In == "body" {
+				typ = "object"
+			}
+			var required string
+			if param.Required {
+				required = " (required)"
+			}
+			out += fmt.Sprintf("  - %s [%s] %s%s\n", param.Name, param.In, typ, required)
+		}
+	}
+	if len(op.Responses) != 0 {
+		out += "Responses   :\n"
+		for _, code := range op.StatusCodes() {
+			resp := op.Responses[code]
+			out += fmt.Sprintf("  - %s: %s\n", code, resp.Description)
+		}
+	}
+	return out
 }